@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kdada/gond/finder"
+	"github.com/spf13/cobra"
+)
+
+var jobs = 1
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "resolve json lines of queries from stdin",
+	Long: `batch reads queries like {"path": "a.go", "pos": 10, "op": "definition"}
+from stdin line by line, and writes a json result per line in the same order.
+Ops are definition and names of query subcommands: typedef, refs, implements,
+jumps, callers, callees, rename, outline, symbols, complete, type and highlight.
+Renames return edits and don't write files. Queries are forwarded to the
+daemon by --daemon.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if jobs < 1 {
+			fatal(usageError{fmt.Errorf("--jobs must be positive")})
+		}
+		finder, _ := setup()
+		if err := batch(finder, bufio.NewScanner(os.Stdin), json.NewEncoder(os.Stdout)); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+// batch resolves queries scanned by scanner with up to jobs queries in
+// flight, and encodes results in the order of queries
+func batch(f *finder.Finder, scanner *bufio.Scanner, encoder *json.Encoder) error {
+	// the finder is shared so parsed files are reused by all queries
+	slots := make(chan struct{}, jobs)
+	pending := make(chan chan *result, jobs)
+	written := make(chan error, 1)
+	go func() {
+		var err error
+		for ch := range pending {
+			r := <-ch
+			if err == nil {
+				err = encoder.Encode(r)
+			}
+		}
+		written <- err
+	}()
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		if len(line) == 0 {
+			continue
+		}
+		ch := make(chan *result, 1)
+		pending <- ch
+		slots <- struct{}{}
+		go func() {
+			defer func() { <-slots }()
+			q := &query{}
+			if err := json.Unmarshal(line, q); err != nil {
+				ch <- &result{Error: newQueryError(usageError{err})}
+				return
+			}
+			if abs, err := filepath.Abs(q.Path); err == nil && q.Path != "" {
+				// the daemon may run in another directory
+				q.Path = abs
+			}
+			if q.DocFormat == "" {
+				q.DocFormat = docFormat
+			}
+			if q.Roots == nil {
+				q.Roots = roots
+			}
+			r := resolve(f, q)
+			r.Query = q
+			ch <- r
+		}()
+	}
+	close(pending)
+	if err := <-written; err != nil {
+		return err
+	}
+	return scanner.Err()
+}
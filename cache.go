@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "manage the on-disk symbol index cache",
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "remove the cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, _ := setup()
+		if err := finder.CleanCache(); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "show statistics of the cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, _ := setup()
+		stats, err := finder.CacheStats()
+		if err != nil {
+			fatal(err)
+		}
+		if format == formatJSON {
+			if err := json.NewEncoder(os.Stdout).Encode(stats); err != nil {
+				fatal(err)
+			}
+			return
+		}
+		fmt.Printf("dir: %s\npackages: %d\nbytes: %d\n", stats.Dir, stats.Packages, stats.Bytes)
+	},
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kdada/gond/finder"
+	"github.com/spf13/cobra"
+)
+
+var depth = 1
+
+var callersCmd = &cobra.Command{
+	Use:   "callers",
+	Short: "find functions calling the function",
+	Run: func(cmd *cobra.Command, args []string) {
+		if depth < 1 {
+			fatal(usageError{fmt.Errorf("--depth must be positive")})
+		}
+		finder, path, pos, printer := prepare()
+		q := newQuery(opCallers, path, pos)
+		q.Depth = depth
+		r := mustResolve(finder, q)
+		printer.output(r, func(w io.Writer) error {
+			return printer.printCalls(w, r.Call, true)
+		})
+	},
+}
+
+var calleesCmd = &cobra.Command{
+	Use:   "callees",
+	Short: "find functions called by the function",
+	Run: func(cmd *cobra.Command, args []string) {
+		if depth < 1 {
+			fatal(usageError{fmt.Errorf("--depth must be positive")})
+		}
+		finder, path, pos, printer := prepare()
+		q := newQuery(opCallees, path, pos)
+		q.Depth = depth
+		r := mustResolve(finder, q)
+		printer.output(r, func(w io.Writer) error {
+			return printer.printCalls(w, r.Call, false)
+		})
+	},
+}
+
+// printCalls prints a call tree to w. Calls of root are its callers if incoming is true
+func (p *printer) printCalls(w io.Writer, root *finder.Call, incoming bool) error {
+	switch p.format {
+	case formatDot:
+		fmt.Fprintln(w, "digraph calls {")
+		printEdges(w, root, incoming, make(map[string]bool))
+		_, err := fmt.Fprintln(w, "}")
+		return err
+	}
+	locations := make([]location, 0)
+	var walk func(call *finder.Call, level int)
+	walk = func(call *finder.Call, level int) {
+		pos := call.Range.Start
+		if incoming && len(call.Sites) > 0 {
+			pos = call.Sites[0].Start
+		}
+		text := strings.Repeat("  ", level) + call.Name
+		if call.Dynamic {
+			text += " (dynamic)"
+		}
+		locations = append(locations, location{call, pos, text})
+		for _, c := range call.Calls {
+			walk(c, level+1)
+		}
+	}
+	walk(root, 0)
+	return p.printLocations(w, locations)
+}
+
+// printEdges prints edges of a call tree in dot language
+func printEdges(w io.Writer, call *finder.Call, incoming bool, printed map[string]bool) {
+	for _, c := range call.Calls {
+		from, to := call.Name, c.Name
+		if incoming {
+			from, to = to, from
+		}
+		edge := fmt.Sprintf("\t%q -> %q", from, to)
+		if c.Dynamic {
+			edge += " [style=dashed]"
+		}
+		if !printed[edge] {
+			printed[edge] = true
+			fmt.Fprintln(w, edge+";")
+		}
+		printEdges(w, c, incoming, printed)
+	}
+}
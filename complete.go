@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kdada/gond/finder"
+	"github.com/spf13/cobra"
+)
+
+var completeCmd = &cobra.Command{
+	Use:   "complete",
+	Short: "list completion candidates at the position",
+	Long: `complete lists completion candidates at the position with their kinds,
+signatures and the first sentences of their documents.
+
+Formats godef, emacs and vim-quickfix list the declarations of candidates,
+candidates without declarations are omitted. Format dot is not supported.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, path, pos, printer := prepare()
+		r := mustResolve(finder, newQuery(opComplete, path, pos))
+		printer.output(r, func(w io.Writer) error {
+			return printer.printCompletions(w, r.Completions)
+		})
+	},
+}
+
+// printCompletions prints completion candidates to w
+func (p *printer) printCompletions(w io.Writer, completions []*finder.Completion) error {
+	switch p.format {
+	case formatTemplate:
+		for _, c := range completions {
+			if err := p.tmpl.Execute(w, c); err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	case formatPlain:
+		for _, c := range completions {
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Label, c.Kind, c.Detail, c.Document); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	locations := make([]location, 0, len(completions))
+	for _, c := range completions {
+		if c.Position.File != "" {
+			locations = append(locations, location{c, c.Position, fmt.Sprintf("%s %s", c.Kind, c.Label)})
+		}
+	}
+	return p.printLocations(w, locations)
+}
@@ -0,0 +1,326 @@
+package finder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+)
+
+// Call describes a function in a call hierarchy
+type Call struct {
+	Name       string `json:"name"`
+	ImportPath string `json:"importPath"`
+	Path       string `json:"path"`
+	Range      Range  `json:"range"`
+	// Sites are ranges of call expressions in the caller
+	Sites []Range `json:"sites,omitempty"`
+	// Dynamic means the function is a candidate implementation of an interface method
+	Dynamic bool `json:"dynamic"`
+	// Calls are callers or callees of the function
+	Calls []*Call `json:"calls,omitempty"`
+}
+
+// FindCallers finds functions calling the function at file:pos, up to depth levels
+func (f *Finder) FindCallers(file string, pos int, depth int) (*Call, error) {
+	f, done := f.begin()
+	defer done()
+	fn, pkg, err := f.findFunc(file, pos)
+	if err != nil {
+		return nil, err
+	}
+	root := f.funcCall(fn)
+	f.callers(root, fn, pkg, file, depth, map[*types.Func]bool{fn: true})
+	return root, nil
+}
+
+// FindCallees finds functions called by the function at file:pos, up to depth levels
+func (f *Finder) FindCallees(file string, pos int, depth int) (*Call, error) {
+	f, done := f.begin()
+	defer done()
+	fn, _, err := f.findFunc(file, pos)
+	if err != nil {
+		return nil, err
+	}
+	root := f.funcCall(fn)
+	f.callees(root, fn, file, depth, map[*types.Func]bool{fn: true})
+	return root, nil
+}
+
+// findFunc finds the function at file:pos
+func (f *Finder) findFunc(file string, pos int) (*types.Func, *Package, error) {
+	obj, pkg, err := f.FindObject(file, pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s is not a function", ErrNotFound, obj.Name())
+	}
+	return fn.Origin(), pkg, nil
+}
+
+// callers adds callers of fn to call
+func (f *Finder) callers(call *Call, fn *types.Func, pkg *Package, file string, depth int, visited map[*types.Func]bool) {
+	if depth <= 0 {
+		return
+	}
+	type caller struct {
+		call *Call
+		fn   *types.Func
+		pkg  *Package
+	}
+	callers := make(map[ast.Node]*caller)
+	for _, p := range f.searchPackages(fn, pkg, file) {
+		for ident, obj := range p.Info.Uses {
+			used, ok := obj.(*types.Func)
+			if !ok || used.Origin() != fn {
+				continue
+			}
+			ce := f.callOf(ident)
+			if ce == nil {
+				continue
+			}
+			node, name := f.enclosingFuncNode(ident)
+			if node == nil {
+				// calls in initializers of package level variables
+				continue
+			}
+			c, ok := callers[node]
+			if !ok {
+				c = &caller{pkg: p}
+				if fd, ok := node.(*ast.FuncDecl); ok {
+					c.fn, _ = p.Info.Defs[fd.Name].(*types.Func)
+				}
+				if c.fn != nil {
+					c.call = f.funcCall(c.fn)
+				} else {
+					c.call = &Call{
+						Name:       p.Types.Name() + "." + name,
+						ImportPath: p.ImportPath,
+						Path:       f.position(node.Pos()).String(),
+						Range:      f.rangeOf(node),
+					}
+				}
+				callers[node] = c
+			}
+			c.call.Sites = append(c.call.Sites, f.rangeOf(ce))
+		}
+	}
+	for _, c := range callers {
+		sortRanges(c.call.Sites)
+		call.Calls = append(call.Calls, c.call)
+		if c.fn != nil && !visited[c.fn] {
+			visited[c.fn] = true
+			f.callers(c.call, c.fn, c.pkg, file, depth-1, visited)
+			delete(visited, c.fn)
+		}
+	}
+	sortCalls(call.Calls)
+}
+
+// callees adds functions called by fn to call
+func (f *Finder) callees(call *Call, fn *types.Func, file string, depth int, visited map[*types.Func]bool) {
+	if depth <= 0 {
+		return
+	}
+	pkg := f.packageByTypes(fn.Pkg())
+	decl := f.funcDecl(fn)
+	if pkg == nil || decl == nil || decl.Body == nil {
+		return
+	}
+	type callee struct {
+		call *Call
+		fn   *types.Func
+	}
+	callees := make(map[*types.Func]*callee)
+	add := func(fn *types.Func, site Range, dynamic bool) {
+		c, ok := callees[fn]
+		if !ok {
+			c = &callee{f.funcCall(fn), fn}
+			c.call.Dynamic = dynamic
+			callees[fn] = c
+		}
+		c.call.Sites = append(c.call.Sites, site)
+	}
+	loaded := false
+	ast.Inspect(decl.Body, func(node ast.Node) bool {
+		ce, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		ident := calleeIdent(ce.Fun)
+		if ident == nil {
+			return true
+		}
+		used, ok := pkg.Info.Uses[ident].(*types.Func)
+		if !ok {
+			// builtins, conversions and function values
+			return true
+		}
+		used = used.Origin()
+		site := f.rangeOf(ce)
+		recv := used.Type().(*types.Signature).Recv()
+		if recv == nil || !types.IsInterface(recv.Type()) {
+			add(used, site, false)
+			return true
+		}
+		// dynamic calls of interface methods
+		if !loaded {
+			f.importers(nil, f.workspace(file))
+			loaded = true
+		}
+		impls := f.implementers(recv.Type(), used.Name())
+		if len(impls) == 0 {
+			add(used, site, true)
+		}
+		for _, impl := range impls {
+			if m, ok := impl.obj.(*types.Func); ok {
+				add(m, site, true)
+			}
+		}
+		return true
+	})
+	for _, c := range callees {
+		sortRanges(c.call.Sites)
+		call.Calls = append(call.Calls, c.call)
+		if !visited[c.fn] {
+			visited[c.fn] = true
+			f.callees(c.call, c.fn, file, depth-1, visited)
+			delete(visited, c.fn)
+		}
+	}
+	sortCalls(call.Calls)
+}
+
+// funcCall creates a Call of fn
+func (f *Finder) funcCall(fn *types.Func) *Call {
+	call := &Call{
+		Name:  qualifiedName(fn),
+		Path:  f.position(fn.Pos()).String(),
+		Range: f.rangeOfPos(fn.Pos(), fn.Pos()+token.Pos(len(fn.Name()))),
+	}
+	if fn.Pkg() != nil {
+		call.ImportPath = fn.Pkg().Path()
+	}
+	return call
+}
+
+// qualifiedName returns the name of fn qualified by package name and receiver type
+func qualifiedName(fn *types.Func) string {
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		return "(" + types.TypeString(recv.Type(), relativeName(nil)) + ")." + fn.Name()
+	}
+	if fn.Pkg() == nil {
+		return fn.Name()
+	}
+	return fn.Pkg().Name() + "." + fn.Name()
+}
+
+// funcDecl finds the declaration of fn
+func (f *Finder) funcDecl(fn *types.Func) *ast.FuncDecl {
+	file := f.tokenSet.File(fn.Pos())
+	if file == nil {
+		return nil
+	}
+	astFile, err := f.file(file.Name())
+	if err != nil {
+		return nil
+	}
+	for _, decl := range astFile.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Name.Pos() == fn.Pos() {
+			return fd
+		}
+	}
+	return nil
+}
+
+// callOf returns the call expression if ident is the called function
+func (f *Finder) callOf(ident *ast.Ident) *ast.CallExpr {
+	var expr ast.Node = ident
+	for _, node := range f.ancestors(ident) {
+		switch n := node.(type) {
+		case *ast.SelectorExpr:
+			if n.Sel != expr {
+				return nil
+			}
+		case *ast.ParenExpr, *ast.IndexExpr, *ast.IndexListExpr:
+		case *ast.CallExpr:
+			if n.Fun == expr {
+				return n
+			}
+			return nil
+		default:
+			return nil
+		}
+		expr = node
+	}
+	return nil
+}
+
+// calleeIdent returns the ident of the called function in fun
+func calleeIdent(fun ast.Expr) *ast.Ident {
+	for {
+		switch e := fun.(type) {
+		case *ast.Ident:
+			return e
+		case *ast.SelectorExpr:
+			return e.Sel
+		case *ast.ParenExpr:
+			fun = e.X
+		case *ast.IndexExpr:
+			fun = e.X
+		case *ast.IndexListExpr:
+			fun = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// enclosingFuncNode returns the innermost FuncDecl or FuncLit enclosing node and its name.
+// Function literals are named after the enclosing declaration like func1, func2
+func (f *Finder) enclosingFuncNode(node ast.Node) (ast.Node, string) {
+	parents := f.ancestors(node)
+	for i, n := range parents {
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			return fn, funcName(fn)
+		case *ast.FuncLit:
+			for _, p := range parents[i+1:] {
+				if fd, ok := p.(*ast.FuncDecl); ok {
+					index := 0
+					ast.Inspect(fd, func(node ast.Node) bool {
+						if fl, ok := node.(*ast.FuncLit); ok && fl.Pos() <= fn.Pos() {
+							index++
+						}
+						return true
+					})
+					return fn, funcName(fd) + ".func" + strconv.Itoa(index)
+				}
+			}
+			return fn, "func"
+		}
+	}
+	return nil, ""
+}
+
+// sortRanges sorts ranges by positions
+func sortRanges(ranges []Range) {
+	sort.Slice(ranges, func(i, j int) bool {
+		a, b := ranges[i].Start, ranges[j].Start
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Offset < b.Offset
+	})
+}
+
+// sortCalls sorts calls by names
+func sortCalls(calls []*Call) {
+	sort.Slice(calls, func(i, j int) bool {
+		return calls[i].Name < calls[j].Name
+	})
+}
@@ -0,0 +1,342 @@
+package finder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// Completion describes a candidate of code completion
+type Completion struct {
+	Label  string `json:"label"`
+	Kind   Kind   `json:"kind"`
+	Detail string `json:"detail"`
+	// Document is the first sentence of the document
+	Document string `json:"document"`
+	// Position is the position of the declaration, the file is empty if the
+	// declaration can't be found
+	Position Position `json:"position"`
+}
+
+// placeholder is inserted at the position if there is no identifier, which
+// makes incomplete selectors like x. parsable
+const placeholder = "_"
+
+// Complete lists candidates for the identifier being typed at file:pos:
+// members after selectors and visible names otherwise
+func (f *Finder) Complete(file string, pos int) ([]*Completion, error) {
+	f, done := f.begin()
+	defer done()
+	src, err := f.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if pos < 0 || pos > len(src) {
+		return nil, fmt.Errorf("%w: %d", ErrOffset, pos)
+	}
+	if !completable(src, pos) {
+		return []*Completion{}, nil
+	}
+	start := pos
+	for start > 0 {
+		r, size := utf8.DecodeLastRune(src[:start])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		start -= size
+	}
+	prefix := string(src[start:pos])
+	shift := 0
+	if prefix == "" {
+		src = append(src[:pos:pos], append([]byte(placeholder), src[pos:]...)...)
+		shift = len(placeholder)
+	}
+	pkg, astFile, err := f.checkOverlay(file, src)
+	if err != nil {
+		return nil, err
+	}
+	tf := f.tokenSet.File(astFile.Pos())
+	at := tf.Pos(start)
+	var objects []types.Object
+	nodes, _ := astutil.PathEnclosingInterval(astFile, at, at)
+	if len(nodes) > 1 {
+		if sel, ok := nodes[1].(*ast.SelectorExpr); ok && sel.Sel == nodes[0] {
+			objects, err = members(pkg, sel.X)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if objects == nil {
+		objects = visible(pkg, at)
+	}
+	completions := make([]*Completion, 0, len(objects))
+	for _, obj := range objects {
+		if obj.Name() == "_" || !strings.HasPrefix(strings.ToLower(obj.Name()), strings.ToLower(prefix)) {
+			continue
+		}
+		completion := &Completion{
+			Label:  obj.Name(),
+			Kind:   objectKind(obj),
+			Detail: types.ObjectString(obj, relativeName(pkg.Types)),
+		}
+		if def := f.completionDefinition(obj, tf, pos, shift); def != nil {
+			completion.Document = summary(def.Document)
+			completion.Position = def.NameRange.Start
+		}
+		completions = append(completions, completion)
+	}
+	sort.Slice(completions, func(i, j int) bool {
+		return completions[i].Label < completions[j].Label
+	})
+	return completions, nil
+}
+
+// completable reports whether names can be completed at pos of src, which
+// is not the case in comments and before the end of the package clause.
+// Positions after code in files without package clauses are completable, the
+// missing clause is reported as a syntax error
+func completable(src []byte, pos int) bool {
+	fset := token.NewFileSet()
+	tf := fset.AddFile("", -1, len(src))
+	var s scanner.Scanner
+	s.Init(tf, src, nil, scanner.ScanComments)
+	clause, code := false, false
+	for {
+		p, tok, lit := s.Scan()
+		if tok == token.EOF || tf.Offset(p) >= pos {
+			break
+		}
+		start := tf.Offset(p)
+		switch {
+		case tok == token.COMMENT:
+			// line comments end at line ends, where the cursor is still in them
+			end := start + len(lit)
+			if pos < end || pos == end && strings.HasPrefix(lit, "//") {
+				return false
+			}
+		case tok == token.PACKAGE && !code:
+			clause, code = true, true
+		case clause && tok == token.IDENT:
+			// the package name is not completed
+			if pos <= start+len(lit) {
+				return false
+			}
+			clause = false
+		default:
+			code = true
+		}
+	}
+	return code && !clause
+}
+
+// checkOverlay type checks the package of file, using src as the content of file
+func (f *Finder) checkOverlay(file string, src []byte) (*Package, *ast.File, error) {
+	file = absPath(file)
+	astFile, err := parser.ParseFile(f.tokenSet, file, src, parser.ParseComments|parser.AllErrors)
+	if astFile == nil || astFile.Name.Name == "" {
+		// files without package clauses can't be type checked
+		return nil, nil, parseError(file, err)
+	}
+	// the overlay is only used by the query
+	f.lock.Lock()
+	f.discard(f.tokenSet.File(astFile.Pos()))
+	f.keep(astFile)
+	f.lock.Unlock()
+	dir := filepath.Dir(file)
+	pkg := &Package{
+		Dir:        dir,
+		ImportPath: f.importPath(dir),
+		Name:       astFile.Name.Name,
+		Files:      []*ast.File{astFile},
+		Info:       newInfo(),
+	}
+	files, err := f.packageSources(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range files {
+		if name == file {
+			continue
+		}
+		// files with syntax errors are skipped
+		if other, err := f.file(name); err == nil && other.Name.Name == pkg.Name {
+			pkg.Files = append(pkg.Files, other)
+		}
+	}
+	f.packageLock.Lock()
+	f.check(pkg)
+	f.pin(f.closure(pkg))
+	f.packageLock.Unlock()
+	return pkg, astFile, nil
+}
+
+// members returns fields and methods of the value x, or exported names of the package x
+func members(pkg *Package, x ast.Expr) ([]types.Object, error) {
+	if ident, ok := x.(*ast.Ident); ok {
+		if pn, ok := pkg.Info.Uses[ident].(*types.PkgName); ok {
+			objects := make([]types.Object, 0)
+			scope := pn.Imported().Scope()
+			for _, name := range scope.Names() {
+				if obj := scope.Lookup(name); obj.Exported() {
+					objects = append(objects, obj)
+				}
+			}
+			return objects, nil
+		}
+	}
+	tv, ok := pkg.Info.Types[x]
+	if !ok || tv.Type == nil || tv.Type == types.Typ[types.Invalid] {
+		return nil, fmt.Errorf("%w: can't infer the type of %s", ErrNotFound, types.ExprString(x))
+	}
+	objects := make([]types.Object, 0)
+	accessible := func(obj types.Object) bool {
+		return obj.Exported() || obj.Pkg() == pkg.Types
+	}
+	if !tv.IsType() {
+		for _, field := range fieldsOf(tv.Type) {
+			if accessible(field) {
+				objects = append(objects, field)
+			}
+		}
+	}
+	t := tv.Type
+	if _, ok := t.Underlying().(*types.Pointer); !ok && !types.IsInterface(t) {
+		// values are assumed to be addressable
+		t = types.NewPointer(t)
+	}
+	ms := types.NewMethodSet(t)
+	for i := 0; i < ms.Len(); i++ {
+		if m := ms.At(i).Obj(); accessible(m) {
+			objects = append(objects, m)
+		}
+	}
+	return objects, nil
+}
+
+// fieldsOf returns fields of struct type t including promoted fields. Fields
+// at shallower depths hide deeper fields with the same name
+func fieldsOf(t types.Type) []types.Object {
+	fields := make([]types.Object, 0)
+	seen := make(map[string]bool)
+	visited := make(map[types.Type]bool)
+	level := []types.Type{t}
+	for len(level) > 0 {
+		next := make([]types.Type, 0)
+		for _, t := range level {
+			t = derefType(t)
+			if visited[t] {
+				continue
+			}
+			visited[t] = true
+			st, ok := t.Underlying().(*types.Struct)
+			if !ok {
+				continue
+			}
+			for i := 0; i < st.NumFields(); i++ {
+				field := st.Field(i)
+				if !seen[field.Name()] {
+					seen[field.Name()] = true
+					fields = append(fields, field)
+				}
+				if field.Embedded() {
+					next = append(next, field.Type())
+				}
+			}
+		}
+		level = next
+	}
+	return fields
+}
+
+// visible returns names visible at pos, inner declarations hide outer ones
+func visible(pkg *Package, pos token.Pos) []types.Object {
+	objects := make([]types.Object, 0)
+	seen := make(map[string]bool)
+	pkgScope := pkg.Types.Scope()
+	for s := pkgScope.Innermost(pos); s != nil; s = s.Parent() {
+		// local names are visible after their declarations
+		local := s != types.Universe && s != pkgScope && s.Parent() != pkgScope
+		for _, name := range s.Names() {
+			obj := s.Lookup(name)
+			if seen[name] || local && obj.Pos() >= pos {
+				continue
+			}
+			seen[name] = true
+			objects = append(objects, obj)
+		}
+	}
+	return objects
+}
+
+// objectKind returns the kind of a types object
+func objectKind(obj types.Object) Kind {
+	switch o := obj.(type) {
+	case *types.Func:
+		if o.Type().(*types.Signature).Recv() != nil {
+			return KindMethod
+		}
+		return KindFunc
+	case *types.Var:
+		if o.IsField() {
+			return KindField
+		}
+		return KindVar
+	case *types.Const:
+		return KindConst
+	case *types.TypeName:
+		return KindType
+	case *types.PkgName:
+		return KindPackage
+	case *types.Label:
+		return KindLabel
+	}
+	return KindBuiltin
+}
+
+// completionDefinition returns the definition of obj, or nil if it can't be
+// found. Objects in the overlay tf are mapped back to the file on disk,
+// shifted by the placeholder after pos
+func (f *Finder) completionDefinition(obj types.Object, tf *token.File, pos, shift int) *Definition {
+	var def *Definition
+	var err error
+	switch {
+	case obj.Pkg() == nil:
+		def, err = f.builtin(obj.Name())
+	case obj.Pos().IsValid() && f.tokenSet.File(obj.Pos()) == tf:
+		offset := tf.Offset(obj.Pos())
+		if offset >= pos {
+			offset -= shift
+		}
+		def, err = f.objectDefinitionAt(obj, tf.Name(), offset)
+	default:
+		def, err = f.ObjectDefinition(obj)
+	}
+	if err != nil {
+		return nil
+	}
+	return def
+}
+
+// summary returns the first sentence of the first paragraph of doc
+func summary(doc string) string {
+	doc = strings.TrimSpace(doc)
+	if i := strings.Index(doc, "\n\n"); i >= 0 {
+		doc = doc[:i]
+	}
+	doc = strings.Join(strings.Fields(doc), " ")
+	if i := strings.Index(doc, ". "); i >= 0 {
+		doc = doc[:i+1]
+	}
+	return doc
+}
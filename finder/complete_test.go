@@ -0,0 +1,60 @@
+package finder
+
+import (
+	"errors"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const libSource = `// Package lib is a library
+package lib
+
+func run() {
+	r
+}
+`
+
+func TestCompleteOutsideCode(t *testing.T) {
+	gopath, dir := workspace(t)
+	lib := filepath.Join(dir, "lib", "lib.go")
+	if err := os.MkdirAll(filepath.Dir(lib), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lib, []byte(libSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := NewFinder(gopath, build.Default.GOROOT)
+
+	cases := map[string]int{
+		"the file start":    0,
+		"a leading comment": 1,
+		"the comment end":   strings.Index(libSource, "\n"),
+		"the package name":  strings.Index(libSource, "lib\n") + 1,
+	}
+	for name, pos := range cases {
+		completions, err := f.Complete(lib, pos)
+		if err != nil || len(completions) != 0 {
+			t.Errorf("completions at %s: %d, %v, want none", name, len(completions), err)
+		}
+	}
+
+	pos := strings.Index(libSource, "\tr\n") + len("\tr")
+	completions, err := f.Complete(lib, pos)
+	if err != nil || len(completions) == 0 || completions[0].Label != "real" && completions[0].Label != "recover" {
+		t.Errorf("completions of r: %v, %v", completions, err)
+	}
+
+	// files being written may have no package clause yet
+	src := []byte("func run() {\n\tr\n}\n")
+	f.SetOverlay(lib, src)
+	var parse *ErrParse
+	if _, err := f.Complete(lib, len("func run() {\n\tr")); !errors.As(err, &parse) {
+		t.Errorf("completions without package clause: %v, want syntax errors", err)
+	}
+	if completions, err := f.Complete(lib, 0); err != nil || len(completions) != 0 {
+		t.Errorf("completions at the start without package clause: %d, %v, want none", len(completions), err)
+	}
+}
@@ -0,0 +1,73 @@
+package finder
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// diffContext is the number of context lines in unified diffs
+const diffContext = 3
+
+// Diff returns the unified diff of the change. Edits never add or remove lines,
+// so lines of both versions are compared one by one
+func (c *Change) Diff() string {
+	before, after := splitLines(c.Before), splitLines(c.After)
+	if len(before) != len(after) {
+		return ""
+	}
+	changed := make([]int, 0)
+	for i := range before {
+		if before[i] != after[i] {
+			changed = append(changed, i)
+		}
+	}
+	var b bytes.Buffer
+	if len(changed) == 0 {
+		return ""
+	}
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", c.File, c.File)
+	for i := 0; i < len(changed); {
+		// merge changed lines whose contexts overlap into one hunk
+		j := i
+		for j+1 < len(changed) && changed[j+1]-changed[j] <= 2*diffContext {
+			j++
+		}
+		start := max(changed[i]-diffContext, 0)
+		end := min(changed[j]+diffContext+1, len(before))
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+		for k, line := start, i; k < end; k++ {
+			if line <= j && changed[line] == k {
+				writeLine(&b, "-", before[k])
+				writeLine(&b, "+", after[k])
+				line++
+				continue
+			}
+			writeLine(&b, " ", before[k])
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+// splitLines splits text into lines including line endings
+func splitLines(text []byte) []string {
+	lines := make([]string, 0)
+	for len(text) > 0 {
+		i := bytes.IndexByte(text, '\n')
+		if i < 0 {
+			i = len(text) - 1
+		}
+		lines = append(lines, string(text[:i+1]))
+		text = text[i+1:]
+	}
+	return lines
+}
+
+// writeLine writes a line of unified diff
+func writeLine(b *bytes.Buffer, prefix, line string) {
+	b.WriteString(prefix)
+	b.WriteString(line)
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		b.WriteString("\n\\ No newline at end of file\n")
+	}
+}
@@ -0,0 +1,203 @@
+package finder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc/comment"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DocFormat describes the format of Definition.Document
+type DocFormat string
+
+// Available document formats
+const (
+	DocText     DocFormat = "text"
+	DocMarkdown DocFormat = "markdown"
+	DocHTML     DocFormat = "html"
+)
+
+// docURL is the base url of doc links in markdown and html documents
+const docURL = "https://pkg.go.dev"
+
+// ParseDocFormat checks and converts a string to DocFormat
+func ParseDocFormat(format string) (DocFormat, error) {
+	switch df := DocFormat(format); df {
+	case DocText, DocMarkdown, DocHTML:
+		return df, nil
+	}
+	return "", fmt.Errorf("unknown doc format: %s", format)
+}
+
+// document finds doc comments of decl and renders them
+func (f *Finder) document(decl ast.Node) (string, error) {
+	groups := f.comments(decl)
+	texts := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if group != nil {
+			texts = append(texts, group.Text())
+		}
+	}
+	text := strings.Join(texts, "\n")
+	if strings.TrimSpace(text) == "" {
+		return "", nil
+	}
+	file, err := f.fileByPos(decl.Pos())
+	if err != nil {
+		return "", err
+	}
+	parser := &comment.Parser{
+		LookupPackage: f.lookupPackage(file),
+		LookupSym:     f.lookupSym(file),
+	}
+	printer := &comment.Printer{
+		DocLinkURL: func(link *comment.DocLink) string {
+			return link.DefaultURL(docURL)
+		},
+	}
+	doc := parser.Parse(text)
+	var result []byte
+	switch f.DocFormat {
+	case DocMarkdown:
+		result = printer.Markdown(doc)
+	case DocHTML:
+		result = printer.HTML(doc)
+	default:
+		result = printer.Text(doc)
+	}
+	return strings.TrimRight(string(result), "\n"), nil
+}
+
+// comments returns comment groups attached to decl
+func (f *Finder) comments(decl ast.Node) []*ast.CommentGroup {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		return []*ast.CommentGroup{d.Doc}
+	case *ast.TypeSpec:
+		if d.Doc != nil {
+			return []*ast.CommentGroup{d.Doc}
+		}
+		return []*ast.CommentGroup{f.genDeclDoc(d)}
+	case *ast.ValueSpec:
+		if d.Doc != nil {
+			return []*ast.CommentGroup{d.Doc}
+		}
+		return []*ast.CommentGroup{f.genDeclDoc(d)}
+	case *ast.Field:
+		return []*ast.CommentGroup{d.Doc, d.Comment}
+	}
+	return nil
+}
+
+// genDeclDoc returns doc of the GenDecl which contains only spec
+func (f *Finder) genDeclDoc(spec ast.Spec) *ast.CommentGroup {
+	if gd := f.genDecl(spec); gd != nil && len(gd.Specs) == 1 {
+		return gd.Doc
+	}
+	return nil
+}
+
+// lookupPackage resolves package names in doc links by imports of file
+func (f *Finder) lookupPackage(file *ast.File) func(name string) (string, bool) {
+	return func(name string) (string, bool) {
+		if name == file.Name.Name {
+			return "", true
+		}
+		for _, spec := range file.Imports {
+			if importName(spec) == name {
+				path, err := strconv.Unquote(spec.Path.Value)
+				return path, err == nil
+			}
+		}
+		return "", false
+	}
+}
+
+// lookupSym reports whether a symbol exists in the package of file, the
+// package is searched when the first doc link is met
+func (f *Finder) lookupSym(file *ast.File) func(recv, name string) bool {
+	var lookup func(recv, name string) bool
+	return func(recv, name string) bool {
+		if lookup == nil {
+			lookup = f.symbolLookup(file)
+		}
+		return lookup(recv, name)
+	}
+}
+
+// symbolLookup searches symbols in the package of file, using the index of
+// the package if possible
+func (f *Finder) symbolLookup(file *ast.File) func(recv, name string) bool {
+	index, err := f.PackageIndex(filepath.Dir(f.position(file.Pos()).Filename))
+	if err == nil && index.Name == file.Name.Name {
+		return func(recv, name string) bool {
+			for _, entry := range index.Symbols {
+				if recv == "" && entry.Name == name ||
+					recv != "" && entry.Kind == KindMethod && entry.Receiver == recv && strings.HasSuffix(entry.Name, "."+name) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return func(recv, name string) bool {
+		for _, pf := range f.packageFiles(file, nil) {
+			if recv == "" {
+				if lookupDecl(pf, name) != nil {
+					return true
+				}
+				continue
+			}
+			for _, decl := range pf.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if ok && fd.Name.Name == name && receiverName(fd) == recv {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// packageFiles returns parsed files in the directory of file which belong to
+// the same package and match build constraints, other files are parsed as
+// skeletons. If match is not nil and the on-disk cache is enabled, only files
+// which the index of the package tells declaring symbols accepted by match are
+// parsed
+func (f *Finder) packageFiles(file *ast.File, match func(entry *IndexEntry) bool) []*ast.File {
+	files := []*ast.File{file}
+	path := f.position(file.Pos()).Filename
+	dir := filepath.Dir(path)
+	var sources []string
+	var err error
+	indexed := match != nil && f.CacheDir != ""
+	if indexed {
+		sources, err = f.indexedSources(dir, file.Name.Name, match)
+	}
+	if !indexed || err != nil {
+		sources, err = f.packageSources(dir)
+	}
+	if err != nil {
+		return files
+	}
+	for _, filePath := range sources {
+		if filePath == path {
+			continue
+		}
+		pf, err := f.skeleton(filePath)
+		if err == nil && pf.Name.Name == file.Name.Name {
+			files = append(files, pf)
+		}
+	}
+	return files
+}
+
+// receiverName returns the type name of the receiver of fd
+func receiverName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return ""
+	}
+	return typeName(fd.Recv.List[0].Type)
+}
@@ -0,0 +1,95 @@
+package finder
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+	"strings"
+)
+
+// Errors returned by finder
+var (
+	// ErrNoIdentifier means there is no identifier at the position
+	ErrNoIdentifier = errors.New("can't find identifier")
+	// ErrNotFound means the definition of the identifier can't be resolved
+	ErrNotFound = errors.New("can't find definition")
+	// ErrOffset means the byte offset is out of the file
+	ErrOffset = errors.New("offset out of range")
+)
+
+// ErrNoIdentAtPos means there is no identifier at the byte offset Pos of File
+type ErrNoIdentAtPos struct {
+	File string
+	Pos  int
+}
+
+// Error implements error
+func (e *ErrNoIdentAtPos) Error() string {
+	return fmt.Sprintf("%s at %s#%d", ErrNoIdentifier, e.File, e.Pos)
+}
+
+// Is reports whether target is ErrNoIdentifier
+func (e *ErrNoIdentAtPos) Is(target error) bool {
+	return target == ErrNoIdentifier
+}
+
+// ErrUnresolved means an identifier in a selector chain can't be resolved.
+// Chain holds names resolved before Name
+type ErrUnresolved struct {
+	Chain  []string
+	Name   string
+	Reason string
+}
+
+// Error implements error
+func (e *ErrUnresolved) Error() string {
+	names := append(append([]string{}, e.Chain...), e.Name)
+	return fmt.Sprintf("%s: can't resolve %s: %s", ErrNotFound, strings.Join(names, "."), e.Reason)
+}
+
+// Is reports whether target is ErrNotFound
+func (e *ErrUnresolved) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ErrPackageNotFound means the directory of the package imported as Path
+// isn't in any of the searched Dirs
+type ErrPackageNotFound struct {
+	Path string
+	Dirs []string
+}
+
+// Error implements error
+func (e *ErrPackageNotFound) Error() string {
+	return fmt.Sprintf("%s: can't find pkg dir: %s", ErrNotFound, e.Path)
+}
+
+// Is reports whether target is ErrNotFound
+func (e *ErrPackageNotFound) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// ErrParse means File has syntax errors
+type ErrParse struct {
+	File   string
+	Errors scanner.ErrorList
+}
+
+// Error implements error
+func (e *ErrParse) Error() string {
+	return e.Errors.Error()
+}
+
+// Unwrap returns the scanner errors
+func (e *ErrParse) Unwrap() error {
+	return e.Errors
+}
+
+// parseError converts an error of the parser to ErrParse
+func parseError(file string, err error) error {
+	var list scanner.ErrorList
+	if errors.As(err, &list) {
+		return &ErrParse{File: file, Errors: list}
+	}
+	return err
+}
@@ -1,10 +1,11 @@
 package finder
 
 import (
-	"fmt"
+	"bufio"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -25,37 +26,61 @@ func walk(path string, f func(filePath string)) error {
 	})
 }
 
-func findDirectory(goRoot, goPath, srcDirectory, pkg string) (string, error) {
-	path := filepath.SplitList(srcDirectory)
-	path = append(path, "", "vendor")
-	count := len(path)
-	dest := ""
-	for count > 1 {
-		path[count-2] = path[count-1]
-		path = path[:count-1]
-		vendor := filepath.Join(path...)
-		pkgPath := filepath.Join(vendor, pkg)
-		if pkgDir(pkgPath) == nil {
-			dest = pkgPath
+// findDirectory finds the directory of pkg imported by files in srcDirectory
+func (f *Finder) findDirectory(srcDirectory, pkg string) (string, error) {
+	// searched directories are reported if the package is not found
+	searched := make([]string, 0)
+	found := func(pkgPath string) bool {
+		searched = append(searched, pkgPath)
+		return pkgDir(pkgPath) == nil
+	}
+	// vendor directories from srcDirectory upwards
+	dir := srcDirectory
+	for {
+		pkgPath := filepath.Join(dir, "vendor", filepath.FromSlash(pkg))
+		if found(pkgPath) {
+			return pkgPath, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
 			break
 		}
+		dir = parent
 	}
-	if dest == "" {
-		pkgPath := filepath.Join(goPath, pkg)
-		if pkgDir(pkgPath) == nil {
-			dest = pkgPath
-		}
+	roots := f.srcRoots()
+	// standard library
+	pkgPath := filepath.Join(roots[0], filepath.FromSlash(pkg))
+	if found(pkgPath) {
+		return pkgPath, nil
 	}
-	if dest == "" {
-		pkgPath := filepath.Join(goRoot, pkg)
-		if pkgDir(pkgPath) == nil {
-			dest = pkgPath
+	// current module and its requirements in the module cache
+	if modDir, modPath := findModule(srcDirectory); modDir != "" {
+		if pkg == modPath || strings.HasPrefix(pkg, modPath+"/") {
+			pkgPath := filepath.Join(modDir, filepath.FromSlash(strings.TrimPrefix(pkg, modPath)))
+			if found(pkgPath) {
+				return pkgPath, nil
+			}
+		}
+		for path, version := range moduleRequires(filepath.Join(modDir, "go.mod")) {
+			if pkg != path && !strings.HasPrefix(pkg, path+"/") {
+				continue
+			}
+			for _, gopath := range filepath.SplitList(f.GOPATH) {
+				pkgPath := filepath.Join(gopath, "pkg", "mod", escapePath(path)+"@"+version, filepath.FromSlash(strings.TrimPrefix(pkg, path)))
+				if found(pkgPath) {
+					return pkgPath, nil
+				}
+			}
 		}
 	}
-	if dest == "" {
-		return "", fmt.Errorf("can't find pkg dir: %s", pkg)
+	// GOPATH
+	for _, root := range roots[1:] {
+		pkgPath := filepath.Join(root, filepath.FromSlash(pkg))
+		if found(pkgPath) {
+			return pkgPath, nil
+		}
 	}
-	return "", nil
+	return "", &ErrPackageNotFound{Path: pkg, Dirs: searched}
 }
 
 func pkgDir(pkgPath string) error {
@@ -65,3 +90,248 @@ func pkgDir(pkgPath string) error {
 	}
 	return err
 }
+
+// srcRoots returns src directories of GOROOT and GOPATH
+func (f *Finder) srcRoots() []string {
+	roots := []string{filepath.Join(f.GOROOT, "src")}
+	for _, path := range filepath.SplitList(f.GOPATH) {
+		roots = append(roots, filepath.Join(path, "src"))
+	}
+	return roots
+}
+
+// importPath returns the import path of the package in dir
+func (f *Finder) importPath(dir string) string {
+	roots := f.srcRoots()
+	if rel, ok := relative(roots[0], dir); ok {
+		return stripVendor(rel)
+	}
+	if modDir, modPath := findModule(dir); modDir != "" {
+		rel, _ := relative(modDir, dir)
+		return stripVendor(filepath.ToSlash(filepath.Join(modPath, rel)))
+	}
+	for _, root := range roots[1:] {
+		if rel, ok := relative(root, dir); ok {
+			return stripVendor(rel)
+		}
+	}
+	return ""
+}
+
+// absPath returns the absolute and clean path. Files and packages are keyed
+// by absolute paths, so a file is parsed and type checked under one name
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// relative returns the slash separated path of dir relative to root
+func relative(root, dir string) (string, bool) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return "", true
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// stripVendor removes the vendor prefix of an import path
+func stripVendor(path string) string {
+	if i := strings.LastIndex(path, "/vendor/"); i >= 0 {
+		return path[i+len("/vendor/"):]
+	}
+	return strings.TrimPrefix(path, "vendor/")
+}
+
+// findModule finds the nearest go.mod from dir upwards and returns its directory and module path
+func findModule(dir string) (string, string) {
+	for {
+		if modPath := modulePath(filepath.Join(dir, "go.mod")); modPath != "" {
+			return dir, modPath
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// modulePath reads the module path from a go.mod file
+func modulePath(file string) string {
+	fd, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer fd.Close()
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "module" {
+			continue
+		}
+		path := fields[1]
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		}
+		return path
+	}
+	return ""
+}
+
+// moduleRequires reads required modules and their versions from a go.mod file
+func moduleRequires(file string) map[string]string {
+	requires := make(map[string]string)
+	fd, err := os.Open(file)
+	if err != nil {
+		return requires
+	}
+	defer fd.Close()
+	scanner := bufio.NewScanner(fd)
+	block := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case block && fields[0] == ")":
+			block = false
+		case block && len(fields) >= 2:
+			requires[fields[0]] = fields[1]
+		case fields[0] == "require" && len(fields) == 2 && fields[1] == "(":
+			block = true
+		case fields[0] == "require" && len(fields) >= 3:
+			requires[fields[1]] = fields[2]
+		}
+	}
+	return requires
+}
+
+// escapePath escapes upper case letters of a module path in the module cache
+func escapePath(path string) string {
+	var b strings.Builder
+	for _, r := range path {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return filepath.FromSlash(b.String())
+}
+
+// workspace returns the workspace roots for queries from file
+func (f *Finder) workspace(file string) []string {
+	return f.workspaceOf(filepath.Dir(file))
+}
+
+// workspaceOf returns the workspace roots for queries from dir
+func (f *Finder) workspaceOf(dir string) []string {
+	if len(f.Roots) > 0 {
+		return f.Roots
+	}
+	dir = absPath(dir)
+	if modDir, _ := findModule(dir); modDir != "" {
+		return []string{modDir}
+	}
+	for _, root := range f.srcRoots()[1:] {
+		// packages in GOPATH may be imported by any package of the same GOPATH,
+		// but walking the whole GOPATH costs too much. Only the project is
+		// searched, Roots widen the search
+		if rel, ok := relative(root, dir); ok {
+			return []string{projectRoot(root, rel)}
+		}
+	}
+	return []string{dir}
+}
+
+// vcsDirs are metadata directories of version control systems
+var vcsDirs = []string{".git", ".hg", ".svn", ".bzr"}
+
+// repoHosts are hosts with import paths of repositories like host/org/repo
+var repoHosts = []string{"github.com", "gitlab.com", "bitbucket.org"}
+
+// projectRoot returns the root of the project containing the directory rel
+// under the GOPATH source root src. It's the outermost version control root,
+// otherwise the repository on a known host or the top directory of rel
+func projectRoot(src, rel string) string {
+	if rel == "" {
+		return src
+	}
+	parts := strings.Split(rel, "/")
+	for i := 1; i <= len(parts); i++ {
+		dir := filepath.Join(src, filepath.FromSlash(strings.Join(parts[:i], "/")))
+		for _, vcs := range vcsDirs {
+			if _, err := os.Stat(filepath.Join(dir, vcs)); err == nil {
+				return dir
+			}
+		}
+	}
+	n := 1
+	for _, host := range repoHosts {
+		if parts[0] == host {
+			n = 3
+		}
+	}
+	if n > len(parts) {
+		n = len(parts)
+	}
+	return filepath.Join(src, filepath.FromSlash(strings.Join(parts[:n], "/")))
+}
+
+// packageDirs returns directories containing go files under roots. Symbolic
+// links to directories are followed, such as repositories linked into GOPATH
+func packageDirs(roots []string) []string {
+	dirs := make([]string, 0)
+	seen := make(map[string]bool)
+	// walked are real paths of walked roots and linked directories
+	walked := make(map[string]bool)
+	var walkDir func(root string)
+	walkDir = func(root string) {
+		real, err := filepath.EvalSymlinks(root)
+		if err != nil || walked[real] {
+			return
+		}
+		walked[real] = true
+		// the trailing separator makes symbolic linked roots walkable
+		root = filepath.Clean(root) + string(filepath.Separator)
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := info.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				if target, err := os.Stat(path); err == nil && target.IsDir() {
+					walkDir(path)
+				}
+				return nil
+			}
+			dir := filepath.Dir(path)
+			if filepath.Ext(name) == ".go" && !strings.HasSuffix(name, "_test.go") && !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+			return nil
+		})
+	}
+	for _, root := range roots {
+		walkDir(root)
+	}
+	return dirs
+}
@@ -0,0 +1,27 @@
+package finder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectRoot(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(src, "example.com", "p", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"":                      src,
+		"example.com/p/sub":     filepath.Join(src, "example.com", "p"),
+		"example.org/q/sub":     filepath.Join(src, "example.org"),
+		"github.com/org/repo/x": filepath.Join(src, "github.com", "org", "repo"),
+		"github.com/org":        filepath.Join(src, "github.com", "org"),
+		"local/pkg":             filepath.Join(src, "local"),
+	}
+	for rel, want := range cases {
+		if root := projectRoot(src, rel); root != want {
+			t.Errorf("project root of %q: %s, want %s", rel, root, want)
+		}
+	}
+}
@@ -1,19 +1,64 @@
 package finder
 
 import (
+	"container/list"
+	"errors"
+	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/scanner"
 	"go/token"
+	"go/types"
+	"path/filepath"
+	"runtime"
+	"sync"
 
 	"golang.org/x/tools/go/ast/astutil"
 )
 
+// Kind describes the kind of a definition
+type Kind string
+
+// Kinds of definitions
+const (
+	KindFunc    Kind = "func"
+	KindMethod  Kind = "method"
+	KindType    Kind = "type"
+	KindField   Kind = "field"
+	KindVar     Kind = "var"
+	KindConst   Kind = "const"
+	KindParam   Kind = "param"
+	KindLabel   Kind = "label"
+	KindPackage Kind = "package"
+	KindBuiltin Kind = "builtin"
+)
+
+// Position describes a position in a source file
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Offset int    `json:"offset"`
+}
+
+// Range describes a range in a source file
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
 // Definition describes definition of an identifier
 type Definition struct {
 	Name        string `json:"name"`
+	Kind        Kind   `json:"kind"`
 	Package     string `json:"package"`
+	ImportPath  string `json:"importPath"`
+	Receiver    string `json:"receiver,omitempty"`
+	Exported    bool   `json:"exported"`
 	Declaration string `json:"declaration"`
 	Path        string `json:"path"`
+	NameRange   Range  `json:"nameRange"`
+	DeclRange   Range  `json:"declRange"`
 	Document    string `json:"document"`
 }
 
@@ -33,83 +78,437 @@ func (id *identDesc) String() string {
 	return id.ident.Name
 }
 
-// Finder describe a finder for searching source code
+// Finder describe a finder for searching source code. A Finder is safe for
+// concurrent use, options should be set before queries or on clones
 type Finder struct {
-	GOPATH   string
-	GOROOT   string
+	GOPATH    string
+	GOROOT    string
+	DocFormat DocFormat
+	// Roots are workspace directories searched by queries across packages
+	Roots []string
+	// CacheDir is the directory of on-disk caches, caches are disabled if empty
+	CacheDir string
+	// MaxFiles is the max number of parsed files kept in memory, no limit if not positive
+	MaxFiles int
+	// MaxMemory is the max estimated memory of parsed files, no limit if not positive
+	MaxMemory int64
+	// Tracer receives steps of resolutions if not nil
+	Tracer Tracer
+	// session is the running query of the finder, if any
+	session *session
+	*cache
+}
+
+// cache holds parsed files and packages shared by clones of a finder
+type cache struct {
 	tokenSet *token.FileSet
+	// lock guards parsed files, overlays and statistics
+	lock     sync.Mutex
 	astFiles map[string]*ast.File
+	// skeletons are files parsed without function bodies, they are kept along
+	// with fully parsed files since their nodes may be in use
+	skeletons map[string]*ast.File
+	// diagnostics are syntax errors of parsed files
+	diagnostics map[string]scanner.ErrorList
+	// parsing are files being parsed, concurrent loads wait for them
+	parsing map[string]*parsing
+	// overlays are contents of files edited in memory
+	overlays map[string][]byte
+	// stamps identify versions of parsed files on disk
+	stamps map[string]stamp
+	// generation increases when files are invalidated
+	generation int
+	// lru orders parsed files by recent use, entries are elements of files
+	lru     *list.List
+	entries map[string]*list.Element
+	// memory is the estimated memory of parsed files
+	memory                  int64
+	hits, misses, evictions int64
+	// indexes are symbol indexes of packages by directories
+	indexes map[string]*Index
+	// sessions is the number of running queries, pins are numbers of running
+	// queries pinning packages by directories
+	sessions int
+	pins     map[string]int
+	// discarded are files of the file set which are no longer cached, they
+	// are removed when no query is running
+	discarded []*token.File
+	// packageLock serializes loading of packages and guards packages
+	packageLock sync.Mutex
+	packages    map[string]*Package
+}
+
+// parsing is a file being parsed
+type parsing struct {
+	done     chan struct{}
+	skeleton bool
+	file     *ast.File
+	err      error
 }
 
 // NewFinder creates a Finder
 func NewFinder(GOPATH, GOROOT string) *Finder {
 	return &Finder{
-		GOPATH,
-		GOROOT,
-		token.NewFileSet(),
-		make(map[string]*ast.File, 0),
+		GOPATH:    GOPATH,
+		GOROOT:    GOROOT,
+		DocFormat: DocText,
+		cache: &cache{
+			tokenSet:    token.NewFileSet(),
+			astFiles:    make(map[string]*ast.File, 0),
+			skeletons:   make(map[string]*ast.File, 0),
+			diagnostics: make(map[string]scanner.ErrorList, 0),
+			parsing:     make(map[string]*parsing, 0),
+			stamps:      make(map[string]stamp, 0),
+			lru:         list.New(),
+			entries:     make(map[string]*list.Element, 0),
+			packages:    make(map[string]*Package, 0),
+			overlays:    make(map[string][]byte, 0),
+			indexes:     make(map[string]*Index, 0),
+			pins:        make(map[string]int, 0),
+		},
 	}
 }
 
+// Clone returns a finder sharing parsed files and packages with f, whose
+// options can be changed independently
+func (f *Finder) Clone() *Finder {
+	clone := *f
+	return &clone
+}
+
 // AddFile append a file to finder
 func (f *Finder) AddFile(file string) error {
-	_, ok := f.astFiles[file]
-	if ok {
-		return nil
+	_, err := f.file(file)
+	return err
+}
+
+// file returns the parsed file. Concurrent loads of the same file are parsed once
+func (f *Finder) file(file string) (*ast.File, error) {
+	return f.load(file, false)
+}
+
+// load returns the parsed file, a skeleton is enough if skeleton is true
+func (f *Finder) load(file string, skeleton bool) (*ast.File, error) {
+	file = absPath(file)
+	f.lock.Lock()
+	for {
+		if astFile := f.cached(file, skeleton); astFile != nil {
+			f.lru.MoveToFront(f.entries[file])
+			f.hits++
+			f.keep(astFile)
+			f.lock.Unlock()
+			return astFile, nil
+		}
+		p, ok := f.parsing[file]
+		if !ok {
+			break
+		}
+		f.lock.Unlock()
+		<-p.done
+		if skeleton || !p.skeleton {
+			f.lock.Lock()
+			f.keep(p.file)
+			f.lock.Unlock()
+			return p.file, p.err
+		}
+		// a skeleton is not enough, wait for or parse the full file
+		f.lock.Lock()
 	}
-	astFile, err := parser.ParseFile(f.tokenSet, file, nil, parser.ParseComments)
-	if err != nil {
-		return err
+	f.misses++
+	p := &parsing{done: make(chan struct{}), skeleton: skeleton}
+	f.parsing[file] = p
+	generation := f.generation
+	f.lock.Unlock()
+	if skeleton {
+		f.trace(EventLoad, token.NoPos, "parse skeleton of %s", file)
+	} else {
+		f.trace(EventLoad, token.NoPos, "parse %s", file)
 	}
-	f.astFiles[file] = astFile
-	return nil
+
+	var st stamp
+	var list scanner.ErrorList
+	p.file, st, list, p.err = f.parse(file, skeleton)
+	f.lock.Lock()
+	delete(f.parsing, file)
+	if p.err == nil && generation == f.generation {
+		if old, ok := f.stamps[file]; ok && old.hash != st.hash {
+			// the other version of the file is out of date
+			f.forget(file)
+		}
+		if e, ok := f.entries[file]; ok {
+			f.lru.MoveToFront(e)
+		} else {
+			f.entries[file] = f.lru.PushFront(file)
+		}
+		f.memory -= f.footprint(file)
+		if skeleton {
+			f.skeletons[file] = p.file
+		} else {
+			f.astFiles[file] = p.file
+		}
+		f.stamps[file] = st
+		if len(list) > 0 {
+			f.diagnostics[file] = list
+		}
+		f.memory += f.footprint(file)
+	} else if p.err == nil {
+		// the file is invalidated while parsing, it is used but not cached
+		f.discard(f.tokenSet.File(p.file.Pos()))
+	}
+	f.keep(p.file)
+	f.lock.Unlock()
+	close(p.done)
+	return p.file, p.err
 }
 
-func (f *Finder) file(file string) (*ast.File, error) {
-	astFile, ok := f.astFiles[file]
-	if ok {
-		return astFile, nil
+// cached returns the fully parsed file, or its skeleton if skeleton is true,
+// with the lock held
+func (f *Finder) cached(file string, skeleton bool) *ast.File {
+	if astFile, ok := f.astFiles[file]; ok {
+		return astFile
+	}
+	if skeleton {
+		return f.skeletons[file]
 	}
-	err := f.AddFile(file)
+	return nil
+}
+
+// parse parses file and returns the stamp of its content and its syntax
+// errors. Files with syntax errors are kept as partial syntax trees, and
+// skeletons are parsed without object resolution and function bodies
+func (f *Finder) parse(file string, skeleton bool) (*ast.File, stamp, scanner.ErrorList, error) {
+	st, src, err := f.stampOf(file)
 	if err != nil {
-		return nil, err
+		return nil, st, nil, err
+	}
+	mode := parser.ParseComments
+	if skeleton {
+		mode |= parser.SkipObjectResolution
+	}
+	astFile, err := parser.ParseFile(f.tokenSet, file, src, mode)
+	var list scanner.ErrorList
+	if err != nil && (!errors.As(err, &list) || astFile == nil || astFile.Name.Name == "") {
+		// files without package clauses can't be resolved
+		return nil, st, nil, parseError(file, err)
 	}
-	astFile, _ = f.astFiles[file]
-	return astFile, nil
+	if skeleton {
+		stripBodies(astFile)
+	}
+	return astFile, st, list, nil
 }
 
+// parseFiles parses files in parallel by a bounded pool of workers, files
+// are parsed as skeletons if skeleton is true
+func (f *Finder) parseFiles(files []string, skeleton bool) ([]*ast.File, error) {
+	astFiles := make([]*ast.File, len(files))
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, file := range files {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			astFiles[i], errs[i] = f.load(file, skeleton)
+			<-slots
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return astFiles, nil
+}
+
+// fileByPos returns the parsed file containing pos, which may be a skeleton
 func (f *Finder) fileByPos(pos token.Pos) (*ast.File, error) {
 	tf := f.tokenSet.File(pos)
-	astFile, err := f.file(tf.Name())
-	if err != nil {
-		return nil, err
+	if tf == nil {
+		return nil, fmt.Errorf("invalid position: %d", pos)
+	}
+	if astFile := f.astFileOf(tf); astFile != nil {
+		return astFile, nil
+	}
+	return f.file(tf.Name())
+}
+
+// astFileOf returns the cached or packaged file parsed as tf, or the file
+// used by the running query
+func (f *Finder) astFileOf(tf *token.File) *ast.File {
+	f.lock.Lock()
+	if f.session != nil && f.session.files[tf] != nil {
+		astFile := f.session.files[tf]
+		f.lock.Unlock()
+		return astFile
+	}
+	for _, astFile := range []*ast.File{f.astFiles[tf.Name()], f.skeletons[tf.Name()]} {
+		if astFile != nil && f.tokenSet.File(astFile.Pos()) == tf {
+			f.lock.Unlock()
+			return astFile
+		}
 	}
-	return astFile, nil
+	f.lock.Unlock()
+	// the file is evicted or parsed again, find it in loaded packages
+	return f.packageFile(tf)
 }
 
 func (f *Finder) position(pos token.Pos) token.Position {
 	return f.tokenSet.Position(pos)
 }
 
+func (f *Finder) rangeOf(node ast.Node) Range {
+	return f.rangeOfPos(node.Pos(), node.End())
+}
+
+func (f *Finder) rangeOfPos(pos, endPos token.Pos) Range {
+	start, end := f.position(pos), f.position(endPos)
+	return Range{
+		Start: Position{start.Filename, start.Line, start.Column, start.Offset},
+		End:   Position{end.Filename, end.Line, end.Column, end.Offset},
+	}
+}
+
+// nodes returns the path of nodes enclosing the byte offsets [start, end] of file
 func (f *Finder) nodes(file string, start, end int) ([]ast.Node, error) {
 	astFile, err := f.file(file)
 	if err != nil {
 		return nil, err
 	}
-	nodes, _ := astutil.PathEnclosingInterval(astFile, token.Pos(start), token.Pos(end))
+	return f.nodesIn(astFile, start, end)
+}
+
+// nodesIn returns the path of nodes enclosing the byte offsets [start, end]
+// of the parsed file
+func (f *Finder) nodesIn(astFile *ast.File, start, end int) ([]ast.Node, error) {
+	tf := f.tokenSet.File(astFile.Pos())
+	if start < 0 || end < start || end > tf.Size() {
+		if start == end {
+			return nil, fmt.Errorf("%w: %d", ErrOffset, start)
+		}
+		return nil, fmt.Errorf("%w: %d-%d", ErrOffset, start, end)
+	}
+	nodes, _ := astutil.PathEnclosingInterval(astFile, tf.Pos(start), tf.Pos(end))
 	return nodes, nil
 }
 
-func (f *Finder) definition(ident *ast.Ident) (*Definition, error) {
+func (f *Finder) definition(ident *ast.Ident, decl ast.Node) (*Definition, error) {
 	file, err := f.fileByPos(ident.Pos())
 	if err != nil {
 		return nil, err
 	}
+	doc, err := f.document(decl)
+	if err != nil {
+		return nil, err
+	}
+	position := f.position(ident.Pos())
 	return &Definition{
 		Name:        ident.Name,
+		Kind:        f.kind(ident, decl),
 		Package:     file.Name.Name,
+		ImportPath:  f.importPath(filepath.Dir(position.Filename)),
+		Receiver:    f.receiver(decl),
+		Exported:    ident.IsExported(),
 		Declaration: "",
-		Path:        f.position(ident.Pos()).String(),
-		Document:    "",
+		Path:        position.String(),
+		NameRange:   f.rangeOf(ident),
+		DeclRange:   f.rangeOf(decl),
+		Document:    doc,
 	}, nil
 }
+
+// kind returns the kind of ident declared by decl
+func (f *Finder) kind(ident *ast.Ident, decl ast.Node) Kind {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil {
+			return KindMethod
+		}
+		return KindFunc
+	case *ast.TypeSpec:
+		return KindType
+	case *ast.ImportSpec:
+		return KindPackage
+	case *ast.LabeledStmt:
+		return KindLabel
+	case *ast.ValueSpec:
+		if gd := f.genDecl(d); gd != nil && gd.Tok == token.CONST {
+			return KindConst
+		}
+		return KindVar
+	case *ast.Field:
+		nodes := f.ancestors(d)
+		if len(nodes) < 2 {
+			return KindVar
+		}
+		switch nodes[1].(type) {
+		case *ast.StructType:
+			return KindField
+		case *ast.InterfaceType:
+			return KindMethod
+		}
+		return KindParam
+	}
+	return KindVar
+}
+
+// receiver returns the receiver type of a method declared by decl
+func (f *Finder) receiver(decl ast.Node) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return types.ExprString(d.Recv.List[0].Type)
+		}
+	case *ast.Field:
+		if _, ok := d.Type.(*ast.FuncType); !ok {
+			return ""
+		}
+		nodes := f.ancestors(d)
+		if len(nodes) < 3 {
+			return ""
+		}
+		if _, ok := nodes[1].(*ast.InterfaceType); !ok {
+			return ""
+		}
+		if spec, ok := nodes[2].(*ast.TypeSpec); ok {
+			return spec.Name.Name
+		}
+	}
+	return ""
+}
+
+// relativeName qualifies types by package names, except types of pkg. Types
+// of all packages are qualified if pkg is nil
+func relativeName(pkg *types.Package) types.Qualifier {
+	return func(other *types.Package) string {
+		if other == pkg {
+			return ""
+		}
+		return other.Name()
+	}
+}
+
+// ancestors returns the parent chain of node, excluding node itself
+func (f *Finder) ancestors(node ast.Node) []ast.Node {
+	nodes, err := f.Chain(node)
+	if err != nil {
+		return nil
+	}
+	for i, n := range nodes {
+		if n == node {
+			return nodes[i+1:]
+		}
+	}
+	return nil
+}
+
+// genDecl returns the GenDecl containing spec
+func (f *Finder) genDecl(spec ast.Spec) *ast.GenDecl {
+	for _, node := range f.ancestors(spec) {
+		if gd, ok := node.(*ast.GenDecl); ok {
+			return gd
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package finder
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const baseSource = `package sub
+
+// Base is the base
+type Base struct{ N int }
+
+// Do does it
+func (b *Base) Do() int { return b.N }
+
+// Doer does
+type Doer interface{ Do() int }
+`
+
+const useSource = `package p
+
+import "example.com/p/sub"
+
+// Wrap wraps
+type Wrap struct {
+	sub.Base
+	X int
+}
+
+func use() int {
+	var b sub.Base
+	var d sub.Doer = &b
+	w := Wrap{}
+	return w.Base.Do() + d.Do()
+}
+
+func more(w *Wrap) int {
+	return w.N + w.Do()
+}
+`
+
+// workspace writes a GOPATH workspace with packages example.com/p and
+// example.com/p/sub, it returns the GOPATH and the directory of example.com/p
+func workspace(t *testing.T) (string, string) {
+	t.Helper()
+	gopath := t.TempDir()
+	dir := filepath.Join(gopath, "src", "example.com", "p")
+	files := map[string]string{
+		filepath.Join(dir, "a.go"):        useSource,
+		filepath.Join(dir, "sub", "b.go"): baseSource,
+	}
+	for file, src := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return gopath, dir
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	for _, maxFiles := range []int{0, 1} {
+		gopath, dir := workspace(t)
+		a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "sub", "b.go")
+		use := strings.Index(useSource, "sub.Base\n\tX") + len("sub.")
+		decl := strings.Index(baseSource, "Base struct")
+		f := NewFinder(gopath, build.Default.GOROOT)
+		f.MaxFiles = maxFiles
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(4)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					def, err := f.FindDefinition(a, use)
+					if err != nil {
+						t.Errorf("max files %d: definition: %v", maxFiles, err)
+						return
+					}
+					if def.Name != "Base" || def.NameRange.Start.File != b || def.NameRange.Start.Offset != decl {
+						t.Errorf("max files %d: definition of Base at %s", maxFiles, def.Path)
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					refs, err := f.FindReferences(b, decl)
+					if err != nil {
+						t.Errorf("max files %d: references: %v", maxFiles, err)
+						return
+					}
+					// the declaration, uses in a.go and the embedded field selected by w.Base
+					if len(refs) != 4 {
+						t.Errorf("max files %d: %d references to Base, want 4", maxFiles, len(refs))
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 20; j++ {
+					f.Refresh()
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				// overlays append to the file, offsets of symbols are kept
+				for j := 0; j < 20; j++ {
+					f.SetOverlay(a, []byte(useSource+"\n// edited\n"))
+					f.RemoveOverlay(a)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
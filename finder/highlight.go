@@ -0,0 +1,125 @@
+package finder
+
+import (
+	"go/ast"
+	"sort"
+)
+
+// Occurrence describes an occurrence of a symbol in a file
+type Occurrence struct {
+	Name   string `json:"name"`
+	Access Access `json:"access"`
+	Range  Range  `json:"range"`
+}
+
+// Highlight finds occurrences of the symbol at file:pos in the same file. It
+// only parses the file: locals are matched by scopes, fields and methods are
+// matched by names, and names declared in other files are matched if unresolved
+func (f *Finder) Highlight(file string, pos int) ([]*Occurrence, error) {
+	f, done := f.begin()
+	defer done()
+	ident, err := f.FindIdent(file, pos)
+	if err != nil {
+		return nil, err
+	}
+	astFile, err := f.file(file)
+	if err != nil {
+		return nil, err
+	}
+	occurrences := make([]*Occurrence, 0)
+	if f.isLabel(ident) {
+		label, err := f.findLabel(ident)
+		if err != nil {
+			return nil, err
+		}
+		occurrences = append(occurrences, f.occurrence(label.Label, AccessDeclaration))
+		f.inspectFunc(label, func(node ast.Node) {
+			if branch, ok := node.(*ast.BranchStmt); ok && branch.Label != nil && branch.Label.Name == ident.Name {
+				occurrences = append(occurrences, f.occurrence(branch.Label, AccessRead))
+			}
+		})
+		return occurrences, nil
+	}
+	members, declared := memberIdents(astFile)
+	var match func(other *ast.Ident) bool
+	switch {
+	case members[ident]:
+		match = func(other *ast.Ident) bool {
+			return members[other] && other.Name == ident.Name
+		}
+	case ident.Obj != nil:
+		match = func(other *ast.Ident) bool {
+			return !members[other] && other.Obj == ident.Obj
+		}
+	default:
+		match = func(other *ast.Ident) bool {
+			return !members[other] && other.Obj == nil && other.Name == ident.Name
+		}
+	}
+	ast.Inspect(astFile, func(node ast.Node) bool {
+		other, ok := node.(*ast.Ident)
+		if !ok || !match(other) {
+			return true
+		}
+		access := f.access(other)
+		if declared[other] || other.Obj != nil && other.Obj.Pos() == other.Pos() {
+			access = AccessDeclaration
+		}
+		occurrences = append(occurrences, f.occurrence(other, access))
+		return true
+	})
+	sort.Slice(occurrences, func(i, j int) bool {
+		return occurrences[i].Range.Start.Offset < occurrences[j].Range.Start.Offset
+	})
+	return occurrences, nil
+}
+
+// occurrence creates an occurrence of ident
+func (f *Finder) occurrence(ident *ast.Ident, access Access) *Occurrence {
+	return &Occurrence{Name: ident.Name, Access: access, Range: f.rangeOf(ident)}
+}
+
+// memberIdents collects idents of fields and methods in file: selectors, keys
+// of composite literals and declarations. Declarations are also returned separately
+func memberIdents(file *ast.File) (map[*ast.Ident]bool, map[*ast.Ident]bool) {
+	members := make(map[*ast.Ident]bool)
+	declared := make(map[*ast.Ident]bool)
+	declare := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				members[name] = true
+				declared[name] = true
+			}
+		}
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.SelectorExpr:
+			members[n.Sel] = true
+		case *ast.StructType:
+			declare(n.Fields)
+		case *ast.InterfaceType:
+			declare(n.Methods)
+		case *ast.FuncDecl:
+			if n.Recv != nil {
+				members[n.Name] = true
+				declared[n.Name] = true
+			}
+		case *ast.CompositeLit:
+			for _, elt := range n.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				if key, ok := kv.Key.(*ast.Ident); ok && key.Obj == nil {
+					members[key] = true
+				}
+			}
+		}
+		return true
+	})
+	return members, declared
+}
@@ -2,18 +2,53 @@ package finder
 
 import (
 	"container/list"
+	"errors"
 	"fmt"
 	"go/ast"
+	"go/token"
 
 	"golang.org/x/tools/go/ast/astutil"
 )
 
 // FindDefinition finds definition
 func (f *Finder) FindDefinition(file string, pos int) (*Definition, error) {
+	f, done := f.begin()
+	defer done()
 	ident, err := f.FindIdent(file, pos)
+	var noIdent *ErrNoIdentAtPos
+	if errors.As(err, &noIdent) {
+		// identifiers in bad syntax are scanned from source
+		stack, badErr := f.badIdent(file, pos)
+		if badErr != nil {
+			return nil, err
+		}
+		f.trace(EventIdent, token.NoPos, "%s#%d is in bad syntax, scanned %s", file, pos, stackNames(stack))
+		node, err := f.AnalyseStack(stack)
+		if err != nil {
+			return nil, err
+		}
+		return f.ToDefinition(node)
+	}
 	if err != nil {
 		return nil, err
 	}
+	def, err := f.identDefinition(ident)
+	if err == nil {
+		return def, nil
+	}
+	// fall back to type checking for identifiers out of the package
+	f.trace(EventFallback, ident.Pos(), "resolve %s by type checking: %v", ident.Name, err)
+	obj, _, typesErr := f.FindObject(file, pos)
+	if typesErr != nil {
+		f.trace(EventFallback, ident.Pos(), "type checking failed: %v", typesErr)
+		return nil, err
+	}
+	f.trace(EventType, ident.Pos(), "%s is %s", ident.Name, obj)
+	return f.ObjectDefinition(obj)
+}
+
+// identDefinition finds definition of ident by its declaration in source
+func (f *Finder) identDefinition(ident *ast.Ident) (*Definition, error) {
 	decl, err := f.FindIdentDecl(ident)
 	if err != nil {
 		return nil, err
@@ -24,46 +59,68 @@ func (f *Finder) FindDefinition(file string, pos int) (*Definition, error) {
 // FindIdent finds ident at file:pos
 func (f *Finder) FindIdent(file string, pos int) (*ast.Ident, error) {
 	nodes, err := f.nodes(file, pos, pos)
-	if err == nil && len(nodes) > 0 {
+	if err != nil {
+		return nil, err
+	}
+	return identOf(nodes, file, pos)
+}
+
+// identOf returns the identifier at file:pos from the path of nodes
+// enclosing it
+func identOf(nodes []ast.Node, file string, pos int) (*ast.Ident, error) {
+	if len(nodes) > 0 {
 		ident, ok := nodes[0].(*ast.Ident)
 		if ok {
 			return ident, nil
 		}
 	}
-	return nil, fmt.Errorf("can't find identifier")
+	return nil, &ErrNoIdentAtPos{File: file, Pos: pos}
 }
 
 // Chain find parent chain of node
 func (f *Finder) Chain(node ast.Node) ([]ast.Node, error) {
 	tf := f.tokenSet.File(node.Pos())
-	if tf != nil {
-		af := f.astFiles[tf.Name()]
-		nodes, _ := astutil.PathEnclosingInterval(af, node.Pos(), node.End())
-		return nodes, nil
+	if tf == nil {
+		return nil, fmt.Errorf("can't find node")
 	}
-	return nil, fmt.Errorf("can't find node")
+	af := f.astFileOf(tf)
+	if af == nil {
+		return nil, fmt.Errorf("%s is evicted from the cache", tf.Name())
+	}
+	nodes, _ := astutil.PathEnclosingInterval(af, node.Pos(), node.End())
+	return nodes, nil
 }
 
 // FindIdentDecl finds ident decl
-func (f *Finder) FindIdentDecl(ident *ast.Ident) (ast.Node, error) {
+func (f *Finder) FindIdentDecl(ident *ast.Ident) (node ast.Node, err error) {
+	defer func() {
+		// unexpected shapes of syntax trees fail the resolution only
+		if r := recover(); r != nil {
+			node, err = nil, &ErrUnresolved{Name: ident.Name, Reason: fmt.Sprintf("unexpected syntax: %v", r)}
+		}
+	}()
 	stack := list.New()
 	nodes, err := f.Chain(ident)
 	if err != nil {
 		return nil, err
 	}
 	if len(nodes) < 2 {
-		return nil, fmt.Errorf("ident is not a valid node")
+		return nil, &ErrUnresolved{Name: ident.Name, Reason: "not a valid node"}
 	}
-	if _, ok := nodes[1].(*ast.SelectorExpr); ok && ident.Obj == nil {
+	if f.isLabel(ident) {
+		label, err := f.findLabel(ident)
+		if err != nil {
+			return nil, err
+		}
+		f.trace(EventIdent, ident.Pos(), "%s is a label", ident.Name)
+		return bindIdent(label.Label, ast.Lbl, label), nil
+	}
+	if sel, ok := nodes[1].(*ast.SelectorExpr); ok && sel.Sel == ident {
 		f.AnalyseSelector(stack, nodes[1])
 	} else {
 		stack.PushBack(ident)
 	}
-	node, err := f.AnalyseStack(stack)
-	if err != nil {
-		return nil, err
-	}
-	return node, nil
+	return f.AnalyseStack(stack)
 }
 
 // AnalyseSelector analyses selector
@@ -82,93 +139,118 @@ func (f *Finder) AnalyseSelector(stack *list.List, node ast.Node) {
 		stack.PushBack(n)
 	case *ast.CompositeLit:
 		f.AnalyseSelector(stack, n.Type)
+	case *ast.UnaryExpr:
+		f.AnalyseSelector(stack, n.X)
+	case *ast.ParenExpr:
+		f.AnalyseSelector(stack, n.X)
+	case *ast.FuncType:
+		if n.Results != nil && len(n.Results.List) > 0 {
+			f.AnalyseSelector(stack, n.Results.List[0].Type)
+		}
 	}
 }
 
 // AnalyseStack analyse selector stack
 func (f *Finder) AnalyseStack(stack *list.List) (ast.Node, error) {
+	// names of the selector in source resolved before the current identifier
+	chain := make([]string, 0, stack.Len())
+	source := make(map[*ast.Ident]bool, stack.Len())
+	for e := stack.Front(); e != nil; e = e.Next() {
+		source[e.Value.(*ast.Ident)] = true
+	}
 	for stack.Len() > 1 {
-		ident := stack.Remove(stack.Back()).(*ast.Ident)
-		if ident.Obj != nil {
-			switch decl := ident.Obj.Decl.(type) {
-			case (*ast.AssignStmt):
-				x := -1
-				for i, expr := range decl.Lhs {
-					if e, ok := expr.(*ast.Ident); ok && e.Name == ident.Name {
-						x = i
-						break
-					}
+		f.trace(EventStack, stack.Back().Value.(*ast.Ident).Pos(), "%s", stackNames(stack))
+		popped := stack.Remove(stack.Back()).(*ast.Ident)
+		ident := f.resolve(popped)
+		unresolved := func(reason string, args ...interface{}) error {
+			return &ErrUnresolved{Chain: chain, Name: ident.Name, Reason: fmt.Sprintf(reason, args...)}
+		}
+		if ident.Obj == nil {
+			// the package of a qualified identifier
+			f.trace(EventIdent, ident.Pos(), "%s is not declared in its package, try imports", ident.Name)
+			sel := stack.Remove(stack.Back()).(*ast.Ident)
+			member, err := f.importedDecl(ident, sel)
+			if err != nil {
+				var ue *ErrUnresolved
+				if errors.As(err, &ue) && source[popped] {
+					ue.Chain = append(append([]string{}, chain...), ue.Chain...)
 				}
-				if len(decl.Lhs) == len(decl.Rhs) {
-					f.AnalyseSelector(stack, decl.Rhs[x])
-				} else {
-					// the i-th result of the function
-					fs := list.New()
-					f.AnalyseSelector(fs, decl.Rhs[0].(*ast.CallExpr).Fun)
-					if fs.Len() <= 0 {
-						return nil, fmt.Errorf("can't find any function")
-					}
-					funcDecl, err := f.FindIdentDecl(fs.Front().Value.(*ast.Ident))
-					if err != nil {
-						return nil, err
-					}
-					f.AnalyseSelector(stack, funcDecl.(*ast.FuncDecl).Type.Results.List[x].Type)
+				return nil, err
+			}
+			stack.PushBack(member)
+			if source[popped] {
+				chain = append(chain, ident.Name)
+			}
+			if source[sel] {
+				chain = append(chain, sel.Name)
+			}
+			continue
+		}
+		size := stack.Len()
+		f.trace(EventIdent, ident.Pos(), "%s is declared by %T", ident.Name, ident.Obj.Decl)
+		switch decl := ident.Obj.Decl.(type) {
+		case *ast.AssignStmt:
+			i := -1
+			for j, expr := range decl.Lhs {
+				if e, ok := expr.(*ast.Ident); ok && e.Name == ident.Name {
+					i = j
+					break
 				}
-			case (*ast.ValueSpec):
-				for i, name := range decl.Names {
-					if name.Name == ident.Name {
-						if decl.Type != nil {
-							f.AnalyseSelector(stack, decl.Type)
-						} else {
-							if len(decl.Names) == len(decl.Values) {
-								f.AnalyseSelector(stack, decl.Values[i])
-							} else {
-								// the i-th result of the function
-								fs := list.New()
-								f.AnalyseSelector(fs, decl.Values[0].(*ast.CallExpr).Fun)
-								if fs.Len() <= 0 {
-									return nil, fmt.Errorf("can't find any function")
-								}
-								funcDecl, err := f.FindIdentDecl(fs.Front().Value.(*ast.Ident))
-								if err != nil {
-									return nil, err
-								}
-								f.AnalyseSelector(stack, funcDecl.(*ast.FuncDecl).Type.Results.List[i].Type)
-							}
-
-						}
-					}
+			}
+			if i < 0 {
+				return nil, unresolved("not assigned by its declaration")
+			}
+			if err := f.analyseValue(stack, decl.Rhs, i, len(decl.Lhs)); err != nil {
+				return nil, err
+			}
+		case *ast.ValueSpec:
+			i := -1
+			for j, name := range decl.Names {
+				if name.Name == ident.Name {
+					i = j
+					break
 				}
-			case (*ast.FuncDecl):
-				f.AnalyseSelector(stack, decl.Type.Results.List[0].Type)
-			case (*ast.TypeSpec):
-				// shrink
-				switch dest := decl.Type.(type) {
-				case (*ast.Ident):
-					stack.PushBack(dest)
-				case (*ast.StructType):
-					// file, err := f.fileByPos(decl.Pos())
-					// if err != nil {
-					// 	return nil, err
-					// }
-					// for _, sd := range file.Decls {
-					// 	switch sdi := sd.(type) {
-					// 	case (*ast.GenDecl):
-					// 		for _, field := range sdi.Specs {
-					// 			switch fd := field.(type) {
-					// 			case (*ast.ValueSpec):
-
-					// 			}
-					// 		}
-					// 	}
-					// }
-					// selector := stack.Remove(stack.Back()).(*ast.Ident)
-
+			}
+			if i < 0 {
+				return nil, unresolved("not declared by its declaration")
+			}
+			if decl.Type != nil {
+				f.AnalyseSelector(stack, decl.Type)
+			} else if err := f.analyseValue(stack, decl.Values, i, len(decl.Names)); err != nil {
+				return nil, err
+			}
+		case *ast.FuncDecl:
+			if result := resultType(decl.Type, 0); result != nil {
+				f.AnalyseSelector(stack, result)
+			}
+		case *ast.Field:
+			f.AnalyseSelector(stack, decl.Type)
+		case *ast.TypeSpec:
+			// shrink to the member of the type
+			sel := stack.Remove(stack.Back()).(*ast.Ident)
+			member, err := f.findMember(decl, sel.Name)
+			if err != nil {
+				return nil, &ErrUnresolved{
+					Chain:  chain,
+					Name:   sel.Name,
+					Reason: fmt.Sprintf("%s has no field or method %s", decl.Name.Name, sel.Name),
 				}
-
 			}
-		} else {
-			// TODO
+			f.trace(EventType, member.Pos(), "%s is a member of %s", sel.Name, decl.Name.Name)
+			stack.PushBack(member)
+			if source[sel] {
+				chain = append(chain, sel.Name)
+			}
+			continue
+		default:
+			return nil, unresolved("unexpected declaration %T", decl)
+		}
+		if stack.Len() == size {
+			return nil, unresolved("can't infer its type")
+		}
+		f.trace(EventType, stack.Back().Value.(*ast.Ident).Pos(), "%s resolves to %s", ident.Name, typeNames(stack, stack.Len()-size))
+		if source[popped] {
+			chain = append(chain, ident.Name)
 		}
 	}
 	return stack.Back().Value.(ast.Node), nil
@@ -177,26 +259,112 @@ func (f *Finder) AnalyseStack(stack *list.List) (ast.Node, error) {
 // ToDefinition transforms node to difinition
 func (f *Finder) ToDefinition(node ast.Node) (*Definition, error) {
 	ident, ok := node.(*ast.Ident)
-	if ok && ident.Obj != nil {
+	if ok {
+		ident = f.resolve(ident)
+		if ident.Obj == nil {
+			return f.undeclared(ident)
+		}
 		switch decl := ident.Obj.Decl.(type) {
 		case (*ast.AssignStmt):
 			for _, expr := range decl.Lhs {
 				if e, ok := expr.(*ast.Ident); ok && e.Name == ident.Name {
-					return f.definition(e)
+					return f.definition(e, decl)
 				}
 			}
 		case (*ast.ValueSpec):
 			for _, name := range decl.Names {
 				if name.Name == ident.Name {
-					return f.definition(name)
+					return f.definition(name, decl)
 				}
 			}
 		case (*ast.TypeSpec):
-			return f.definition(decl.Name)
+			return f.definition(decl.Name, decl)
 		case (*ast.FuncDecl):
-			return f.definition(decl.Name)
+			return f.definition(decl.Name, decl)
+		case (*ast.LabeledStmt):
+			return f.definition(decl.Label, decl)
+		case (*ast.Field):
+			if len(decl.Names) == 0 {
+				return f.definition(embeddedIdent(decl.Type), decl)
+			}
+			for _, name := range decl.Names {
+				if name.Name == ident.Name {
+					return f.definition(name, decl)
+				}
+			}
+		}
+		f.trace(EventIdent, ident.Pos(), "%s has unexpected declaration %T", ident.Name, ident.Obj.Decl)
+		return nil, &ErrUnresolved{Name: ident.Name, Reason: fmt.Sprintf("unexpected declaration %T", ident.Obj.Decl)}
+	}
+	return nil, fmt.Errorf("%w: node is not a declaration", ErrNotFound)
+}
+
+// analyseValue pushes the type of the i-th of n identifiers assigned by
+// values to stack
+func (f *Finder) analyseValue(stack *list.List, values []ast.Expr, i, n int) error {
+	if len(values) == n {
+		f.AnalyseSelector(stack, values[i])
+		return nil
+	}
+	if len(values) != 1 {
+		return nil
+	}
+	switch value := ast.Unparen(values[0]).(type) {
+	case *ast.CallExpr:
+		// the i-th result of the function
+		fs := list.New()
+		f.AnalyseSelector(fs, value.Fun)
+		if fs.Len() <= 0 {
+			return nil
+		}
+		node, err := f.FindIdentDecl(fs.Front().Value.(*ast.Ident))
+		if err != nil {
+			return err
+		}
+		fn, ok := node.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		if fn = f.resolve(fn); fn.Obj == nil {
+			return nil
+		}
+		switch decl := fn.Obj.Decl.(type) {
+		case *ast.FuncDecl:
+			if result := resultType(decl.Type, i); result != nil {
+				f.AnalyseSelector(stack, result)
+			}
+		case *ast.Field:
+			// methods of interfaces
+			if ft, ok := decl.Type.(*ast.FuncType); ok {
+				if result := resultType(ft, i); result != nil {
+					f.AnalyseSelector(stack, result)
+				}
+			}
+		}
+	case *ast.TypeAssertExpr:
+		// the value of a comma-ok type assertion
+		if i == 0 && value.Type != nil {
+			f.AnalyseSelector(stack, value.Type)
+		}
+	}
+	// other values, such as ranges and receives, are resolved by types
+	return nil
+}
+
+// resultType returns the type of the i-th result of ft, or nil
+func resultType(ft *ast.FuncType, i int) ast.Expr {
+	if ft == nil || ft.Results == nil {
+		return nil
+	}
+	for _, field := range ft.Results.List {
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		if i < n {
+			return field.Type
 		}
-		ast.Print(f.tokenSet, ident)
+		i -= n
 	}
-	return nil, fmt.Errorf("node is not a declaration")
+	return nil
 }
@@ -0,0 +1,203 @@
+package finder
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// Relation describes the relation between a type and the queried type
+type Relation string
+
+// Relations of implementations
+const (
+	// RelationImplementation means the type implements the queried interface
+	RelationImplementation Relation = "implementation"
+	// RelationInterface means the queried type implements the interface
+	RelationInterface Relation = "interface"
+)
+
+// Implementation describes a type or method in an implementing relation
+type Implementation struct {
+	Name     string   `json:"name"`
+	Kind     Kind     `json:"kind"`
+	Relation Relation `json:"relation"`
+	// Pointer means only the method set of the pointer type satisfies the interface
+	Pointer    bool   `json:"pointer"`
+	ImportPath string `json:"importPath"`
+	Path       string `json:"path"`
+	Range      Range  `json:"range"`
+}
+
+// FindImplementations finds types implementing the interface at file:pos,
+// methods implementing the interface method at file:pos, or interfaces
+// satisfied by the concrete type at file:pos
+func (f *Finder) FindImplementations(file string, pos int) ([]*Implementation, error) {
+	f, done := f.begin()
+	defer done()
+	obj, _, err := f.FindObject(file, pos)
+	if err != nil {
+		return nil, err
+	}
+	// types in the workspace and dependencies
+	f.importers(nil, f.workspace(file))
+	var impls []*Implementation
+	switch o := obj.(type) {
+	case *types.TypeName:
+		if types.IsInterface(o.Type()) {
+			impls = f.implementations(o.Type(), "")
+		} else {
+			impls = f.interfaces(o.Type())
+		}
+	case *types.Func:
+		recv := o.Type().(*types.Signature).Recv()
+		if recv == nil || !types.IsInterface(recv.Type()) {
+			return nil, fmt.Errorf("%w: %s is not an interface method", ErrNotFound, o.Name())
+		}
+		impls = f.implementations(recv.Type(), o.Name())
+	default:
+		return nil, fmt.Errorf("%w: %s is not a type or an interface method", ErrNotFound, obj.Name())
+	}
+	impls = uniqueImplementations(impls)
+	sort.Slice(impls, func(i, j int) bool {
+		if impls[i].Pointer != impls[j].Pointer {
+			return !impls[i].Pointer
+		}
+		return impls[i].Name < impls[j].Name
+	})
+	return impls, nil
+}
+
+// uniqueImplementations removes implementations declared at the same position
+// with the same name, which are found in packages loaded more than once
+func uniqueImplementations(impls []*Implementation) []*Implementation {
+	result := impls[:0]
+	seen := make(map[string]bool)
+	for _, impl := range impls {
+		key := impl.Path + " " + impl.Name
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, impl)
+		}
+	}
+	return result
+}
+
+// implementations finds concrete types implementing iface, or their methods
+// implementing the interface method called method if it is not empty
+func (f *Finder) implementations(iface types.Type, method string) []*Implementation {
+	impls := make([]*Implementation, 0)
+	for _, impl := range f.implementers(iface, method) {
+		impls = append(impls, f.implementation(impl.obj, RelationImplementation, impl.pointer))
+	}
+	return impls
+}
+
+// implementer is a concrete type or method implementing an interface
+type implementer struct {
+	obj types.Object
+	// pointer means only the pointer type implements the interface
+	pointer bool
+}
+
+// implementers finds concrete types implementing iface, or their methods
+// implementing the interface method called method if it is not empty
+func (f *Finder) implementers(iface types.Type, method string) []implementer {
+	it, ok := iface.Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+	var im *types.Func
+	for i := 0; i < it.NumMethods(); i++ {
+		if it.Method(i).Name() == method {
+			im = it.Method(i)
+		}
+	}
+	impls := make([]implementer, 0)
+	for _, tn := range f.namedTypes() {
+		if types.IsInterface(tn.Type()) {
+			continue
+		}
+		pointer := false
+		if !types.Implements(tn.Type(), it) {
+			if !types.Implements(types.NewPointer(tn.Type()), it) {
+				continue
+			}
+			pointer = true
+		}
+		if im == nil {
+			impls = append(impls, implementer{tn, pointer})
+			continue
+		}
+		var recv types.Type = tn.Type()
+		if pointer {
+			recv = types.NewPointer(recv)
+		}
+		if sel := types.NewMethodSet(recv).Lookup(im.Pkg(), method); sel != nil {
+			impls = append(impls, implementer{sel.Obj(), pointer})
+		}
+	}
+	return impls
+}
+
+// interfaces finds interfaces satisfied by t or *t
+func (f *Finder) interfaces(t types.Type) []*Implementation {
+	impls := make([]*Implementation, 0)
+	for _, tn := range f.namedTypes() {
+		it, ok := tn.Type().Underlying().(*types.Interface)
+		if !ok || it.Empty() {
+			continue
+		}
+		if types.Implements(t, it) {
+			impls = append(impls, f.implementation(tn, RelationInterface, false))
+		} else if types.Implements(types.NewPointer(t), it) {
+			impls = append(impls, f.implementation(tn, RelationInterface, true))
+		}
+	}
+	return impls
+}
+
+// namedTypes returns non-generic named types at package level of all loaded packages
+func (f *Finder) namedTypes() []*types.TypeName {
+	names := make([]*types.TypeName, 0)
+	for _, pkg := range f.loadedPackages() {
+		if pkg.Types == nil {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			if named, ok := tn.Type().(*types.Named); ok && named.TypeParams().Len() == 0 {
+				names = append(names, tn)
+			}
+		}
+	}
+	return names
+}
+
+// implementation creates an Implementation of a type or method
+func (f *Finder) implementation(obj types.Object, relation Relation, pointer bool) *Implementation {
+	impl := &Implementation{
+		Kind:       KindType,
+		Relation:   relation,
+		Pointer:    pointer,
+		ImportPath: obj.Pkg().Path(),
+		Path:       f.position(obj.Pos()).String(),
+		Range:      f.rangeOfPos(obj.Pos(), obj.Pos()+token.Pos(len(obj.Name()))),
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		impl.Kind = KindMethod
+		recv := fn.Type().(*types.Signature).Recv().Type()
+		impl.Name = "(" + types.TypeString(recv, relativeName(nil)) + ")." + fn.Name()
+	} else {
+		impl.Name = types.TypeString(obj.Type(), relativeName(nil))
+		if pointer && relation == RelationImplementation {
+			impl.Name = "*" + impl.Name
+		}
+	}
+	return impl
+}
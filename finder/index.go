@@ -0,0 +1,301 @@
+package finder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// indexVersion is the version of the index format, indexes of other versions are rebuilt
+const indexVersion = 1
+
+// Index is the symbol index of a package
+type Index struct {
+	Version    int          `json:"version"`
+	Dir        string       `json:"dir"`
+	ImportPath string       `json:"importPath"`
+	Name       string       `json:"name"`
+	Files      []IndexFile  `json:"files"`
+	Symbols    []IndexEntry `json:"symbols"`
+}
+
+// IndexFile identifies the version of an indexed file
+type IndexFile struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+}
+
+// IndexEntry is a declaration, method or field in an index
+type IndexEntry struct {
+	// Name is qualified by the receiver or owner type, like (*T).M or T.F
+	Name     string `json:"name"`
+	Kind     Kind   `json:"kind"`
+	Receiver string `json:"receiver,omitempty"`
+	Exported bool   `json:"exported"`
+	Range    Range  `json:"range"`
+	// Doc is the first sentence of the document
+	Doc string `json:"doc,omitempty"`
+}
+
+// CacheStats describes the on-disk cache
+type CacheStats struct {
+	Dir      string `json:"dir"`
+	Packages int    `json:"packages"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// PackageIndex returns the symbol index of the package in dir. Indexes are
+// read from CacheDir if files of the package are not changed, otherwise they
+// are built from source and written to CacheDir
+func (f *Finder) PackageIndex(dir string) (*Index, error) {
+	f, done := f.begin()
+	defer done()
+	dir = absPath(dir)
+	files, err := f.packageSources(dir)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]IndexFile, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, IndexFile{filepath.Base(file), info.Size(), info.ModTime().UnixNano()})
+	}
+	overlay := f.hasOverlay(dir)
+	if !overlay {
+		f.lock.Lock()
+		index := f.indexes[dir]
+		f.lock.Unlock()
+		if index != nil && sameFiles(index.Files, stats) {
+			return index, nil
+		}
+	}
+	cached := f.CacheDir != "" && !overlay
+	if cached {
+		if index := f.readIndex(dir); index != nil && sameFiles(index.Files, stats) {
+			f.memoize(index)
+			return index, nil
+		}
+	}
+	index, err := f.buildIndex(dir, files)
+	if err != nil {
+		return nil, err
+	}
+	index.Files = stats
+	if cached {
+		// indexes are only caches, failures of writing are ignored
+		f.writeIndex(index)
+	}
+	if !overlay {
+		f.memoize(index)
+	}
+	return index, nil
+}
+
+// memoize keeps index in memory, it is used until files of the package change
+func (f *Finder) memoize(index *Index) {
+	f.lock.Lock()
+	f.indexes[index.Dir] = index
+	f.lock.Unlock()
+}
+
+// buildIndex parses files of the package in dir and indexes symbols
+func (f *Finder) buildIndex(dir string, files []string) (*Index, error) {
+	astFiles, err := f.parseFiles(files, true)
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{Version: indexVersion, Dir: dir, ImportPath: f.importPath(dir)}
+	for _, astFile := range astFiles {
+		if index.Name == "" {
+			index.Name = astFile.Name.Name
+		}
+		if astFile.Name.Name != index.Name {
+			continue
+		}
+		add := func(name string, ident *ast.Ident, kind Kind, receiver string, docs ...*ast.CommentGroup) {
+			entry := IndexEntry{
+				Name:     name,
+				Kind:     kind,
+				Receiver: receiver,
+				Exported: ident.IsExported(),
+				Range:    f.rangeOf(ident),
+			}
+			for _, doc := range docs {
+				if doc != nil {
+					entry.Doc = summary(doc.Text())
+					break
+				}
+			}
+			index.Symbols = append(index.Symbols, entry)
+		}
+		for _, decl := range astFile.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv != nil {
+					add(funcName(d), d.Name, KindMethod, receiverName(d), d.Doc)
+				} else {
+					add(d.Name.Name, d.Name, KindFunc, "", d.Doc)
+				}
+			case *ast.GenDecl:
+				// documents of groups belong to their only spec
+				var groupDoc *ast.CommentGroup
+				if len(d.Specs) == 1 {
+					groupDoc = d.Doc
+				}
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.ValueSpec:
+						kind := KindVar
+						if d.Tok == token.CONST {
+							kind = KindConst
+						}
+						for _, name := range s.Names {
+							if name.Name != "_" {
+								add(name.Name, name, kind, "", s.Doc, groupDoc, s.Comment)
+							}
+						}
+					case *ast.TypeSpec:
+						add(s.Name.Name, s.Name, KindType, "", s.Doc, groupDoc, s.Comment)
+						var fields *ast.FieldList
+						kind := KindField
+						switch t := s.Type.(type) {
+						case *ast.StructType:
+							fields = t.Fields
+						case *ast.InterfaceType:
+							fields, kind = t.Methods, KindMethod
+						}
+						if fields == nil {
+							continue
+						}
+						for _, field := range fields.List {
+							// embedded fields are found by their types
+							for _, name := range field.Names {
+								add(s.Name.Name+"."+name.Name, name, kind, s.Name.Name, field.Doc, field.Comment)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return index, nil
+}
+
+// hasOverlay reports whether any file in dir has an overlay
+func (f *Finder) hasOverlay(dir string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for file := range f.overlays {
+		if filepath.Dir(file) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFiles reports whether indexed files are the same as files on disk
+func sameFiles(indexed, files []IndexFile) bool {
+	if len(indexed) != len(files) {
+		return false
+	}
+	for i := range files {
+		if indexed[i] != files[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// indexDir returns the directory of indexes in the cache
+func (f *Finder) indexDir() string {
+	return filepath.Join(f.CacheDir, "index")
+}
+
+// indexPath returns the path of the index of the package in dir
+func (f *Finder) indexPath(dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(f.indexDir(), hex.EncodeToString(sum[:16])+".json")
+}
+
+// readIndex reads the cached index of dir, or returns nil if there is no valid index
+func (f *Finder) readIndex(dir string) *Index {
+	data, err := os.ReadFile(f.indexPath(dir))
+	if err != nil {
+		return nil
+	}
+	index := &Index{}
+	if err := json.Unmarshal(data, index); err != nil || index.Version != indexVersion || index.Dir != dir {
+		return nil
+	}
+	return index
+}
+
+// writeIndex writes index to the cache. The index is written to a temporary
+// file and renamed, so concurrent readers never see partial indexes
+func (f *Finder) writeIndex(index *Index) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.indexDir(), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(f.indexDir(), "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.indexPath(index.Dir))
+}
+
+// CacheStats returns statistics of the on-disk cache
+func (f *Finder) CacheStats() (*CacheStats, error) {
+	stats := &CacheStats{Dir: f.CacheDir}
+	if f.CacheDir == "" {
+		return stats, nil
+	}
+	entries, err := os.ReadDir(f.indexDir())
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		stats.Packages++
+		stats.Bytes += info.Size()
+	}
+	return stats, nil
+}
+
+// CleanCache removes the on-disk cache
+func (f *Finder) CleanCache() error {
+	if f.CacheDir == "" {
+		return nil
+	}
+	return os.RemoveAll(f.indexDir())
+}
@@ -0,0 +1,102 @@
+package finder
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// Jump describes a goto, break or continue statement to a label
+type Jump struct {
+	Label string `json:"label"`
+	Token string `json:"token"`
+	Path  string `json:"path"`
+	Range Range  `json:"range"`
+}
+
+// FindLabelJumps finds all jumps to the label at file:pos
+func (f *Finder) FindLabelJumps(file string, pos int) ([]*Jump, error) {
+	f, done := f.begin()
+	defer done()
+	ident, err := f.FindIdent(file, pos)
+	if err != nil {
+		return nil, err
+	}
+	if !f.isLabel(ident) {
+		return nil, fmt.Errorf("%w: %s is not a label", ErrNotFound, ident.Name)
+	}
+	label, err := f.findLabel(ident)
+	if err != nil {
+		return nil, err
+	}
+	jumps := make([]*Jump, 0)
+	f.inspectFunc(label, func(node ast.Node) {
+		branch, ok := node.(*ast.BranchStmt)
+		if ok && branch.Label != nil && branch.Label.Name == ident.Name {
+			jumps = append(jumps, &Jump{
+				Label: ident.Name,
+				Token: branch.Tok.String(),
+				Path:  f.position(branch.Pos()).String(),
+				Range: f.rangeOf(branch),
+			})
+		}
+	})
+	return jumps, nil
+}
+
+// isLabel reports whether ident is the label of a labeled statement or a jump
+func (f *Finder) isLabel(ident *ast.Ident) bool {
+	nodes := f.ancestors(ident)
+	if len(nodes) == 0 {
+		return false
+	}
+	switch parent := nodes[0].(type) {
+	case *ast.LabeledStmt:
+		return parent.Label == ident
+	case *ast.BranchStmt:
+		return parent.Label == ident
+	}
+	return false
+}
+
+// findLabel finds the LabeledStmt of a label ident in the enclosing function.
+// Labels are not visible in function literals
+func (f *Finder) findLabel(ident *ast.Ident) (*ast.LabeledStmt, error) {
+	var label *ast.LabeledStmt
+	f.inspectFunc(ident, func(node ast.Node) {
+		if ls, ok := node.(*ast.LabeledStmt); ok && label == nil && ls.Label.Name == ident.Name {
+			label = ls
+		}
+	})
+	if label == nil {
+		return nil, fmt.Errorf("%w: label %s is not defined", ErrNotFound, ident.Name)
+	}
+	return label, nil
+}
+
+// inspectFunc calls fn for nodes in the body of the function enclosing node,
+// excluding nested function literals
+func (f *Finder) inspectFunc(node ast.Node, fn func(node ast.Node)) {
+	var body *ast.BlockStmt
+	for _, n := range f.ancestors(node) {
+		if fl, ok := n.(*ast.FuncLit); ok {
+			body = fl.Body
+			break
+		}
+		if fd, ok := n.(*ast.FuncDecl); ok {
+			body = fd.Body
+			break
+		}
+	}
+	if body == nil {
+		return
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		if n != nil {
+			fn(n)
+		}
+		return true
+	})
+}
@@ -0,0 +1,202 @@
+package finder
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+)
+
+// astOverhead estimates the memory of a parsed file by times of its source size
+const astOverhead = 10
+
+// skeletonOverhead estimates the memory of a skeleton by times of its source size
+const skeletonOverhead = 3
+
+// Stats describes the memory cache of a finder
+type Stats struct {
+	Files int `json:"files"`
+	// Skeletons are files parsed without function bodies
+	Skeletons int `json:"skeletons"`
+	Packages  int `json:"packages"`
+	Overlays  int `json:"overlays"`
+	// Memory is the estimated memory of parsed files in bytes
+	Memory    int64 `json:"memory"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+}
+
+// Stats returns statistics of parsed files and packages
+func (f *Finder) Stats() *Stats {
+	packages := len(f.loadedPackages())
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return &Stats{
+		Files:     len(f.astFiles),
+		Skeletons: len(f.skeletons),
+		Packages:  packages,
+		Overlays:  len(f.overlays),
+		Memory:    f.memory,
+		Hits:      f.hits,
+		Misses:    f.misses,
+		Evictions: f.evictions,
+	}
+}
+
+// session is a running query. Packages loaded by it are pinned until it ends,
+// since their syntax trees and objects are in use
+type session struct {
+	// dirs are directories of pinned packages
+	dirs map[string]bool
+	// files are files parsed for the query, they are found by positions
+	// even if they are evicted
+	files map[*token.File]*ast.File
+}
+
+// begin starts a query by a clone of f, unless f is running one. The returned
+// function ends the query, then parsed files are trimmed to the budget
+func (f *Finder) begin() (*Finder, func()) {
+	if f.session != nil {
+		return f, func() {}
+	}
+	clone := f.Clone()
+	clone.session = &session{
+		dirs:  make(map[string]bool),
+		files: make(map[*token.File]*ast.File),
+	}
+	f.lock.Lock()
+	f.sessions++
+	f.lock.Unlock()
+	return clone, func() {
+		f.lock.Lock()
+		for dir := range clone.session.dirs {
+			if f.pins[dir]--; f.pins[dir] == 0 {
+				delete(f.pins, dir)
+			}
+		}
+		f.sessions--
+		f.lock.Unlock()
+		f.trim(nil)
+	}
+}
+
+// pin keeps packages in the running query until it ends
+func (f *Finder) pin(packages []*Package) {
+	if f.session == nil {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, pkg := range packages {
+		if !f.session.dirs[pkg.Dir] {
+			f.session.dirs[pkg.Dir] = true
+			f.pins[pkg.Dir]++
+		}
+	}
+}
+
+// keep records astFile used by the running query, with the lock held
+func (f *Finder) keep(astFile *ast.File) {
+	if f.session != nil && astFile != nil {
+		f.session.files[f.tokenSet.File(astFile.Pos())] = astFile
+	}
+}
+
+// closure returns pkg and loaded packages imported by it directly or
+// indirectly, with the package lock held
+func (f *Finder) closure(pkg *Package) []*Package {
+	loaded := make(map[*types.Package]*Package, len(f.packages))
+	for _, p := range f.packages {
+		if p != nil && p.Types != nil {
+			loaded[p.Types] = p
+		}
+	}
+	packages := []*Package{pkg}
+	seen := map[*Package]bool{pkg: true}
+	for i := 0; i < len(packages); i++ {
+		if packages[i].Types == nil {
+			continue
+		}
+		for _, imported := range packages[i].Types.Imports() {
+			if p := loaded[imported]; p != nil && !seen[p] {
+				seen[p] = true
+				packages = append(packages, p)
+			}
+		}
+	}
+	return packages
+}
+
+// discard releases tf from the file set once no query is running, with the
+// lock held
+func (f *Finder) discard(tf *token.File) {
+	if tf != nil {
+		f.discarded = append(f.discarded, tf)
+	}
+}
+
+// trim evicts least recently used files until parsed files fit in the budget
+func (f *Finder) trim(pinned map[string]bool) {
+	f.packageLock.Lock()
+	defer f.packageLock.Unlock()
+	f.trimLocked(pinned)
+}
+
+// trimLocked evicts least recently used files with the package lock held.
+// Files with overlays, pinned files and files of packages pinned by running
+// queries are not evicted. Packages containing evicted files are discarded,
+// so their memory can be released. Files are removed from the file set when
+// no query is running
+func (f *Finder) trimLocked(pinned map[string]bool) {
+	f.lock.Lock()
+	dirs := make([]string, 0)
+	for e := f.lru.Back(); e != nil && f.overBudget(); {
+		file := e.Value.(string)
+		e = e.Prev()
+		if _, ok := f.overlays[file]; ok || pinned[file] || f.pins[filepath.Dir(file)] > 0 {
+			continue
+		}
+		f.forget(file)
+		f.evictions++
+		dirs = append(dirs, filepath.Dir(file))
+	}
+	if f.sessions == 0 {
+		for _, tf := range f.discarded {
+			f.tokenSet.RemoveFile(tf)
+		}
+		f.discarded = nil
+	}
+	f.lock.Unlock()
+	f.dropPackages(dirs)
+}
+
+// footprint returns the estimated memory of a parsed file, with the lock held
+func (f *Finder) footprint(file string) int64 {
+	var memory int64
+	if _, ok := f.astFiles[file]; ok {
+		memory += f.stamps[file].size * astOverhead
+	}
+	if _, ok := f.skeletons[file]; ok {
+		memory += f.stamps[file].size * skeletonOverhead
+	}
+	return memory
+}
+
+// overBudget reports whether parsed files exceed the budget, with the lock held
+func (f *Finder) overBudget() bool {
+	return f.MaxFiles > 0 && len(f.entries) > f.MaxFiles ||
+		f.MaxMemory > 0 && f.memory > f.MaxMemory
+}
+
+// packageFile finds the parsed file of tf in loaded packages
+func (f *Finder) packageFile(tf *token.File) *ast.File {
+	for _, pkg := range f.loadedPackages() {
+		for _, file := range pkg.Files {
+			if f.tokenSet.File(file.Pos()) == tf {
+				return file
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package finder
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// findMember finds the method or field called name of the type declared by spec
+func (f *Finder) findMember(spec *ast.TypeSpec, name string) (*ast.Ident, error) {
+	member := f.member(spec, name, true, make(map[*ast.TypeSpec]bool))
+	if member == nil {
+		return nil, fmt.Errorf("%w: %s has no field or method %s", ErrNotFound, spec.Name.Name, name)
+	}
+	return member, nil
+}
+
+// member finds the method or field called name of spec. Methods are skipped if methods is false
+func (f *Finder) member(spec *ast.TypeSpec, name string, methods bool, visited map[*ast.TypeSpec]bool) *ast.Ident {
+	if visited[spec] {
+		return nil
+	}
+	visited[spec] = true
+	if methods {
+		if method := f.method(spec, name); method != nil {
+			return method
+		}
+	}
+	var embedded []ast.Expr
+	promoted := true
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		for _, field := range t.Fields.List {
+			if len(field.Names) == 0 {
+				if typeName(field.Type) == name {
+					return bindIdent(embeddedIdent(field.Type), ast.Var, field)
+				}
+				embedded = append(embedded, field.Type)
+				continue
+			}
+			for _, n := range field.Names {
+				if n.Name == name {
+					return bindIdent(n, ast.Var, field)
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		for _, field := range t.Methods.List {
+			if len(field.Names) == 0 {
+				embedded = append(embedded, field.Type)
+				continue
+			}
+			for _, n := range field.Names {
+				if n.Name == name {
+					return bindIdent(n, ast.Fun, field)
+				}
+			}
+		}
+	default:
+		// defined types have fields of the underlying type, alias types have its methods too
+		embedded = append(embedded, spec.Type)
+		promoted = spec.Assign.IsValid()
+	}
+	for _, expr := range embedded {
+		ident := f.embeddedType(expr)
+		if ident == nil || ident.Obj == nil {
+			continue
+		}
+		es, ok := ident.Obj.Decl.(*ast.TypeSpec)
+		if !ok {
+			continue
+		}
+		if member := f.member(es, name, promoted, visited); member != nil {
+			return member
+		}
+	}
+	return nil
+}
+
+// method finds the method called name whose receiver is the type declared by spec
+func (f *Finder) method(spec *ast.TypeSpec, name string) *ast.Ident {
+	file, err := f.fileByPos(spec.Pos())
+	if err != nil {
+		return nil
+	}
+	for _, pf := range f.packageFiles(file, declaresMethod(spec.Name.Name, name)) {
+		for _, decl := range pf.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if ok && fd.Name.Name == name && receiverName(fd) == spec.Name.Name {
+				return bindIdent(fd.Name, ast.Fun, fd)
+			}
+		}
+	}
+	return nil
+}
+
+// bindIdent creates an ident bound to decl.
+// Names of methods, fields and labels are not resolved by the parser
+func bindIdent(name *ast.Ident, kind ast.ObjKind, decl ast.Node) *ast.Ident {
+	obj := ast.NewObj(kind, name.Name)
+	obj.Decl = decl
+	return &ast.Ident{
+		NamePos: name.NamePos,
+		Name:    name.Name,
+		Obj:     obj,
+	}
+}
+
+// embeddedIdent returns the type name ident of an embedded field
+func embeddedIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			return e.Sel
+		case *ast.Ident:
+			return e
+		default:
+			return nil
+		}
+	}
+}
+
+// embeddedType binds the type name of an embedded field to its declaration,
+// which may be in other files of the package or in an imported package
+func (f *Finder) embeddedType(expr ast.Expr) *ast.Ident {
+	ident := embeddedIdent(expr)
+	if ident == nil || ident.Obj != nil {
+		return ident
+	}
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			pkg, ok := e.X.(*ast.Ident)
+			if !ok {
+				return ident
+			}
+			if bound, err := f.importedDecl(pkg, e.Sel); err == nil {
+				return bound
+			}
+			return ident
+		default:
+			return f.resolve(ident)
+		}
+	}
+}
+
+// typeName returns the name of the type of an embedded field
+func typeName(expr ast.Expr) string {
+	if ident := embeddedIdent(expr); ident != nil {
+		return ident.Name
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package finder
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// Symbol describes a declared symbol in an outline
+type Symbol struct {
+	Name string `json:"name"`
+	Kind Kind   `json:"kind"`
+	// Detail is a summary of the signature or type
+	Detail    string `json:"detail"`
+	Range     Range  `json:"range"`
+	NameRange Range  `json:"nameRange"`
+	// Children are fields and methods of types
+	Children []*Symbol `json:"children,omitempty"`
+}
+
+// Outline returns symbols declared in file. Methods are grouped under their
+// receiver types if the types are declared in the same file
+func (f *Finder) Outline(file string) ([]*Symbol, error) {
+	f, done := f.begin()
+	defer done()
+	astFile, err := f.file(file)
+	if err != nil {
+		return nil, err
+	}
+	symbols := make([]*Symbol, 0)
+	owners := make(map[string]*Symbol)
+	methods := make([]*ast.FuncDecl, 0)
+	for _, decl := range astFile.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.ImportSpec:
+					symbols = append(symbols, f.importSymbol(s))
+				case *ast.ValueSpec:
+					kind := KindVar
+					if d.Tok == token.CONST {
+						kind = KindConst
+					}
+					for _, name := range s.Names {
+						symbols = append(symbols, f.symbol(name, kind, exprString(s.Type), s))
+					}
+				case *ast.TypeSpec:
+					symbol := f.typeSymbol(s)
+					owners[s.Name.Name] = symbol
+					symbols = append(symbols, symbol)
+				}
+			}
+		case *ast.FuncDecl:
+			if d.Recv != nil {
+				methods = append(methods, d)
+				continue
+			}
+			symbols = append(symbols, f.symbol(d.Name, KindFunc, exprString(d.Type), d))
+		}
+	}
+	for _, fd := range methods {
+		if owner, ok := owners[receiverName(fd)]; ok {
+			owner.Children = append(owner.Children, f.symbol(fd.Name, KindMethod, exprString(fd.Type), fd))
+			continue
+		}
+		symbol := f.symbol(fd.Name, KindMethod, exprString(fd.Type), fd)
+		symbol.Name = funcName(fd)
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// symbol creates a symbol of name declared by decl
+func (f *Finder) symbol(name *ast.Ident, kind Kind, detail string, decl ast.Node) *Symbol {
+	return &Symbol{
+		Name:      name.Name,
+		Kind:      kind,
+		Detail:    detail,
+		Range:     f.rangeOf(decl),
+		NameRange: f.rangeOf(name),
+	}
+}
+
+// importSymbol creates a symbol of an imported package
+func (f *Finder) importSymbol(spec *ast.ImportSpec) *Symbol {
+	path, _ := strconv.Unquote(spec.Path.Value)
+	var name ast.Node = spec.Path
+	if spec.Name != nil {
+		name = spec.Name
+	}
+	return &Symbol{
+		Name:      importName(spec),
+		Kind:      KindPackage,
+		Detail:    path,
+		Range:     f.rangeOf(spec),
+		NameRange: f.rangeOf(name),
+	}
+}
+
+// typeSymbol creates a symbol of a type with its fields or interface methods
+func (f *Finder) typeSymbol(spec *ast.TypeSpec) *Symbol {
+	symbol := f.symbol(spec.Name, KindType, exprString(spec.Type), spec)
+	var fields *ast.FieldList
+	kind := KindField
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		symbol.Detail = "struct"
+		fields = t.Fields
+	case *ast.InterfaceType:
+		symbol.Detail = "interface"
+		fields = t.Methods
+		kind = KindMethod
+	}
+	if fields == nil {
+		return symbol
+	}
+	for _, field := range fields.List {
+		detail := exprString(field.Type)
+		if len(field.Names) == 0 {
+			// embedded fields and embedded interfaces
+			if ident := embeddedIdent(field.Type); ident != nil {
+				symbol.Children = append(symbol.Children, f.symbol(ident, KindField, detail, field))
+			}
+			continue
+		}
+		for _, name := range field.Names {
+			symbol.Children = append(symbol.Children, f.symbol(name, kind, detail, field))
+		}
+	}
+	return symbol
+}
+
+// exprString formats expr, or returns an empty string if expr is nil
+func exprString(expr ast.Expr) string {
+	if expr == nil {
+		return ""
+	}
+	return types.ExprString(expr)
+}
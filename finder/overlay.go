@@ -0,0 +1,38 @@
+package finder
+
+import (
+	"os"
+)
+
+// SetOverlay replaces the content of file with src in memory. The parsed file
+// and packages depending on it are discarded
+func (f *Finder) SetOverlay(file string, src []byte) {
+	file = absPath(file)
+	f.lock.Lock()
+	f.overlays[file] = src
+	f.lock.Unlock()
+	f.invalidate([]string{file})
+}
+
+// RemoveOverlay restores the content of file on disk
+func (f *Finder) RemoveOverlay(file string) {
+	file = absPath(file)
+	f.lock.Lock()
+	_, ok := f.overlays[file]
+	delete(f.overlays, file)
+	f.lock.Unlock()
+	if ok {
+		f.invalidate([]string{file})
+	}
+}
+
+// ReadFile returns the content of file, preferring overlays
+func (f *Finder) ReadFile(file string) ([]byte, error) {
+	f.lock.Lock()
+	src, ok := f.overlays[absPath(file)]
+	f.lock.Unlock()
+	if ok {
+		return src, nil
+	}
+	return os.ReadFile(file)
+}
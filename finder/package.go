@@ -0,0 +1,287 @@
+package finder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Package describes a parsed and type checked package
+type Package struct {
+	Dir string
+	// modTime is the modification time of Dir when the package was loaded
+	modTime    time.Time
+	ImportPath string
+	Name       string
+	Files      []*ast.File
+	Types      *types.Package
+	Info       *types.Info
+}
+
+// importer imports packages from source for type checking
+type importer struct {
+	finder *Finder
+}
+
+// Import imports a package by import path
+func (i *importer) Import(path string) (*types.Package, error) {
+	return i.ImportFrom(path, "", 0)
+}
+
+// ImportFrom imports a package by import path from the directory of the importing file
+func (i *importer) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	pkgDir, err := i.finder.findDirectory(dir, path)
+	if err != nil {
+		return nil, err
+	}
+	i.finder.trace(EventLoad, token.NoPos, "import %s from %s", path, dir)
+	// the package lock is held by the loading package
+	pkg, err := i.finder.loadPackage(pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	return pkg.Types, nil
+}
+
+// LoadPackage parses and type checks the package in dir
+func (f *Finder) LoadPackage(dir string) (*Package, error) {
+	f.packageLock.Lock()
+	defer f.packageLock.Unlock()
+	pkg, err := f.loadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+	// files of the loaded package and its dependencies are kept even if they
+	// exceed the budget, or the package would be discarded with them
+	packages := f.closure(pkg)
+	f.pin(packages)
+	pinned := make(map[string]bool)
+	for _, p := range packages {
+		for _, file := range p.Files {
+			pinned[f.position(file.Pos()).Filename] = true
+		}
+	}
+	f.trimLocked(pinned)
+	return pkg, nil
+}
+
+// loadPackage loads the package in dir with the package lock held
+func (f *Finder) loadPackage(dir string) (*Package, error) {
+	dir = absPath(dir)
+	if pkg, ok := f.packages[dir]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle at %s", dir)
+		}
+		return pkg, nil
+	}
+	files, err := f.packageSources(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: no go files in %s", ErrNotFound, dir)
+	}
+	f.trace(EventLoad, token.NoPos, "type check package %s", dir)
+	// mark as loading to detect import cycles
+	f.packages[dir] = nil
+	pkg := &Package{
+		Dir:        dir,
+		ImportPath: f.importPath(dir),
+		Info:       newInfo(),
+	}
+	if info, err := os.Stat(dir); err == nil {
+		pkg.modTime = info.ModTime()
+	}
+	astFiles, err := f.parseFiles(files, false)
+	if err != nil {
+		delete(f.packages, dir)
+		return nil, err
+	}
+	for _, astFile := range astFiles {
+		if pkg.Name == "" {
+			pkg.Name = astFile.Name.Name
+		}
+		if astFile.Name.Name == pkg.Name {
+			pkg.Files = append(pkg.Files, astFile)
+		}
+	}
+	f.check(pkg)
+	f.packages[dir] = pkg
+	return pkg, nil
+}
+
+// newInfo creates a types info recording all information
+func newInfo() *types.Info {
+	return &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+}
+
+// check type checks files of pkg with the package lock held
+func (f *Finder) check(pkg *Package) {
+	path := pkg.ImportPath
+	if path == "" {
+		path = pkg.Name
+	}
+	config := &types.Config{
+		Importer:    &importer{f},
+		FakeImportC: true,
+		// keep checking on errors, partial information is enough for resolving
+		Error: func(err error) {},
+	}
+	pkg.Types, _ = config.Check(path, f.tokenSet, pkg.Files, pkg.Info)
+}
+
+// packageSources returns go files of the package in dir, excluding test files
+// and files excluded by build constraints
+func (f *Finder) packageSources(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ctx := build.Default
+	ctx.GOROOT = f.GOROOT
+	ctx.GOPATH = f.GOPATH
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".go" || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if ok, err := ctx.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// packageOf loads the package containing file, it returns the package and
+// the parsed file in it
+func (f *Finder) packageOf(file string) (*Package, *ast.File, error) {
+	f.trace(EventLoad, token.NoPos, "load package of %s", file)
+	pkg, err := f.LoadPackage(filepath.Dir(file))
+	if err != nil {
+		return nil, nil, err
+	}
+	// the file is matched by name, it may be evicted and parsed again
+	file = absPath(file)
+	for _, pf := range pkg.Files {
+		if f.position(pf.Pos()).Filename == file {
+			return pkg, pf, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%s is not a part of package %s", file, pkg.Dir)
+}
+
+// objectOf returns the object denoted by ident in pkg
+func (pkg *Package) objectOf(ident *ast.Ident) types.Object {
+	if obj := pkg.Info.Defs[ident]; obj != nil {
+		return obj
+	}
+	return pkg.Info.Uses[ident]
+}
+
+// FindObject finds the types object of the identifier at file:pos
+func (f *Finder) FindObject(file string, pos int) (types.Object, *Package, error) {
+	f, done := f.begin()
+	defer done()
+	// the identifier is found in the file of the package, since the file may
+	// be parsed again after it is evicted
+	pkg, astFile, err := f.packageOf(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes, err := f.nodesIn(astFile, pos, pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	ident, err := identOf(nodes, file, pos)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj := pkg.objectOf(ident)
+	if obj == nil {
+		return nil, nil, fmt.Errorf("%w: %s has no object", ErrNotFound, ident.Name)
+	}
+	return obj, pkg, nil
+}
+
+// ObjectDefinition transforms a types object to definition
+func (f *Finder) ObjectDefinition(obj types.Object) (*Definition, error) {
+	if obj.Pkg() == nil {
+		return f.builtin(obj.Name())
+	}
+	if !obj.Pos().IsValid() {
+		return nil, fmt.Errorf("%w: %s has no position", ErrNotFound, obj.Name())
+	}
+	file := f.tokenSet.File(obj.Pos())
+	if file == nil {
+		return nil, fmt.Errorf("%w: %s is not in a parsed file", ErrNotFound, obj.Name())
+	}
+	return f.objectDefinitionAt(obj, file.Name(), file.Offset(obj.Pos()))
+}
+
+// objectDefinitionAt transforms a types object declared at file:offset to definition
+func (f *Finder) objectDefinitionAt(obj types.Object, file string, offset int) (*Definition, error) {
+	nodes, err := f.nodes(file, offset, offset)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := obj.(*types.PkgName); ok {
+		for _, node := range nodes {
+			if spec, ok := node.(*ast.ImportSpec); ok {
+				return f.importDefinition(spec)
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("%w: can't find declaration of %s", ErrNotFound, obj.Name())
+	}
+	ident, ok := nodes[0].(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("%w: can't find declaration of %s", ErrNotFound, obj.Name())
+	}
+	return f.definition(ident, declOf(ident, nodes[1:]))
+}
+
+// declOf returns the declaration node of ident from its parent chain
+func declOf(ident *ast.Ident, parents []ast.Node) ast.Node {
+	for _, node := range parents {
+		switch decl := node.(type) {
+		case *ast.FuncDecl, *ast.TypeSpec, *ast.ValueSpec, *ast.Field,
+			*ast.AssignStmt, *ast.RangeStmt, *ast.LabeledStmt, *ast.ImportSpec:
+			return decl
+		}
+	}
+	return ident
+}
+
+// loadedPackages returns all loaded packages
+func (f *Finder) loadedPackages() []*Package {
+	f.packageLock.Lock()
+	defer f.packageLock.Unlock()
+	packages := make([]*Package, 0, len(f.packages))
+	for _, pkg := range f.packages {
+		if pkg != nil {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages
+}
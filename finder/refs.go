@@ -0,0 +1,236 @@
+package finder
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Access describes how a reference uses the symbol
+type Access string
+
+// Accesses of references
+const (
+	AccessRead        Access = "read"
+	AccessWrite       Access = "write"
+	AccessDeclaration Access = "declaration"
+)
+
+// Reference describes a use of a symbol
+type Reference struct {
+	Name       string `json:"name"`
+	Access     Access `json:"access"`
+	ImportPath string `json:"importPath"`
+	Func       string `json:"func"`
+	Path       string `json:"path"`
+	Range      Range  `json:"range"`
+}
+
+// FindReferences finds all references to the symbol at file:pos in the
+// defining package and packages in the workspace which import it
+func (f *Finder) FindReferences(file string, pos int) ([]*Reference, error) {
+	f, done := f.begin()
+	defer done()
+	obj, pkg, err := f.FindObject(file, pos)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]*Reference, 0)
+	for _, p := range f.searchPackages(obj, pkg, file) {
+		refs = append(refs, f.references(p, obj)...)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i].Range.Start, refs[j].Range.Start
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Offset < b.Offset
+	})
+	return refs, nil
+}
+
+// searchPackages returns packages which may refer to obj: the defining package
+// and packages in the workspace of file which import it
+func (f *Finder) searchPackages(obj types.Object, pkg *Package, file string) []*Package {
+	packages := []*Package{pkg}
+	if obj.Pkg() != nil && obj.Pkg() != pkg.Types {
+		packages[0] = f.packageByTypes(obj.Pkg())
+	}
+	if packages[0] == nil || obj.Pkg() == nil || visibleOutside(obj) {
+		packages = append(packages, f.importers(obj.Pkg(), f.workspace(file))...)
+	}
+	result := make([]*Package, 0, len(packages))
+	seen := make(map[*Package]bool)
+	for _, p := range packages {
+		if p != nil && !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// references finds references to obj in pkg
+func (f *Finder) references(pkg *Package, obj types.Object) []*Reference {
+	refs := make([]*Reference, 0)
+	add := func(ident *ast.Ident, access Access) {
+		refs = append(refs, &Reference{
+			Name:       ident.Name,
+			Access:     access,
+			ImportPath: pkg.ImportPath,
+			Func:       f.enclosingFunc(ident),
+			Path:       f.position(ident.Pos()).String(),
+			Range:      f.rangeOf(ident),
+		})
+	}
+	for ident, o := range pkg.Info.Defs {
+		if o == obj {
+			add(ident, AccessDeclaration)
+		}
+	}
+	for ident, o := range pkg.Info.Uses {
+		if o == obj {
+			add(ident, f.access(ident))
+		}
+	}
+	return refs
+}
+
+// access reports whether ident is read or written
+func (f *Finder) access(ident *ast.Ident) Access {
+	var expr ast.Node = ident
+	parents := f.ancestors(ident)
+	for len(parents) > 0 {
+		switch p := parents[0].(type) {
+		case *ast.SelectorExpr:
+			if p.Sel != expr {
+				return AccessRead
+			}
+		case *ast.ParenExpr:
+		case *ast.AssignStmt:
+			for _, lhs := range p.Lhs {
+				if lhs == expr {
+					return AccessWrite
+				}
+			}
+			return AccessRead
+		case *ast.IncDecStmt:
+			return AccessWrite
+		case *ast.RangeStmt:
+			if p.Tok == token.ASSIGN && (p.Key == expr || p.Value == expr) {
+				return AccessWrite
+			}
+			return AccessRead
+		case *ast.KeyValueExpr:
+			if _, ok := parentOf(parents).(*ast.CompositeLit); ok && p.Key == expr {
+				// fields in composite literals
+				return AccessWrite
+			}
+			return AccessRead
+		default:
+			return AccessRead
+		}
+		expr = parents[0]
+		parents = parents[1:]
+	}
+	return AccessRead
+}
+
+// parentOf returns the second node in parents
+func parentOf(parents []ast.Node) ast.Node {
+	if len(parents) < 2 {
+		return nil
+	}
+	return parents[1]
+}
+
+// enclosingFunc returns the name of the function declaration enclosing node
+func (f *Finder) enclosingFunc(node ast.Node) string {
+	for _, n := range f.ancestors(node) {
+		if fd, ok := n.(*ast.FuncDecl); ok {
+			return funcName(fd)
+		}
+	}
+	return ""
+}
+
+// funcName returns the name of fd, prefixed by its receiver type for methods
+func funcName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	return "(" + types.ExprString(fd.Recv.List[0].Type) + ")." + fd.Name.Name
+}
+
+// visibleOutside reports whether obj can be used by other packages
+func visibleOutside(obj types.Object) bool {
+	if !obj.Exported() {
+		return false
+	}
+	switch obj.(type) {
+	case *types.PkgName, *types.Label:
+		return false
+	}
+	// package level objects, methods and fields
+	return obj.Parent() == nil || obj.Parent() == obj.Pkg().Scope()
+}
+
+// packageByTypes returns the loaded package of a types package
+func (f *Finder) packageByTypes(tp *types.Package) *Package {
+	for _, pkg := range f.loadedPackages() {
+		if pkg.Types == tp {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// importers loads packages under roots which import tp. All packages are
+// returned if tp is nil
+func (f *Finder) importers(tp *types.Package, roots []string) []*Package {
+	if tp != nil {
+		f.trace(EventLoad, token.NoPos, "load packages importing %s in %s", tp.Path(), strings.Join(roots, ", "))
+	} else {
+		f.trace(EventLoad, token.NoPos, "load packages in %s", strings.Join(roots, ", "))
+	}
+	packages := make([]*Package, 0)
+	for _, dir := range packageDirs(roots) {
+		if tp != nil && !f.imports(dir, tp.Path()) {
+			continue
+		}
+		pkg, err := f.LoadPackage(dir)
+		if err == nil {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages
+}
+
+// imports reports whether any go file in dir imports path. Only import
+// declarations are parsed, files are not cached
+func (f *Finder) imports(dir, path string) bool {
+	files, err := f.packageSources(dir)
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		src, err := f.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		astFile, err := parser.ParseFile(token.NewFileSet(), file, src, parser.ImportsOnly)
+		if astFile == nil {
+			continue
+		}
+		for _, spec := range astFile.Imports {
+			if p, err := strconv.Unquote(spec.Path.Value); err == nil && p == path {
+				return true
+			}
+		}
+	}
+	return false
+}
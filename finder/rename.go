@@ -0,0 +1,355 @@
+package finder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Errors of renames
+var (
+	// ErrConflict means a rename conflicts with existing declarations
+	ErrConflict = errors.New("rename conflict")
+	// ErrName means the symbol can't be renamed to the name
+	ErrName = errors.New("invalid rename")
+)
+
+// Change describes changes of a file
+type Change struct {
+	File   string `json:"file"`
+	Edits  []Edit `json:"edits"`
+	Before []byte `json:"-"`
+	After  []byte `json:"-"`
+}
+
+// Edit describes a replacement of text in a file
+type Edit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// Rename renames the symbol at file:pos to name in the workspace and
+// returns changes of files. Files are not written
+func (f *Finder) Rename(file string, pos int, name string) ([]*Change, error) {
+	f, done := f.begin()
+	defer done()
+	if !token.IsIdentifier(name) {
+		return nil, fmt.Errorf("%w: %q is not a valid identifier", ErrName, name)
+	}
+	if name == "_" {
+		// blank declarations can't be referred to by their uses
+		return nil, fmt.Errorf("%w: can't rename to the blank identifier", ErrName)
+	}
+	obj, pkg, err := f.FindObject(file, pos)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Name() == name {
+		return nil, fmt.Errorf("%w: %s is already named %s", ErrName, obj.Name(), name)
+	}
+	if obj.Pkg() == nil {
+		return nil, fmt.Errorf("%w: can't rename builtin %s", ErrName, obj.Name())
+	}
+	if v, ok := obj.(*types.Var); ok && v.Embedded() {
+		return nil, fmt.Errorf("%w: can't rename embedded field %s, rename its type instead", ErrName, obj.Name())
+	}
+	objects := []types.Object{obj}
+	packages := f.searchPackages(obj, pkg, file)
+	if err := f.checkImporters(obj, packages); err != nil {
+		return nil, err
+	}
+	if obj.Parent() == nil {
+		// methods and fields are checked against all types in the workspace
+		f.importers(nil, f.workspace(file))
+	}
+	if _, ok := obj.(*types.TypeName); ok {
+		objects = append(objects, embeddedFields(packages, obj)...)
+	}
+	var conflicts []string
+	idents := make([]*ast.Ident, 0)
+	for _, p := range packages {
+		for _, o := range objects {
+			for ident, def := range p.Info.Defs {
+				if def == o {
+					idents = append(idents, ident)
+				}
+			}
+			for ident, use := range p.Info.Uses {
+				if use == o {
+					idents = append(idents, ident)
+					conflicts = append(conflicts, f.useConflicts(p, ident, o, name)...)
+				}
+			}
+		}
+		conflicts = append(conflicts, f.captureConflicts(p, obj, name)...)
+	}
+	conflicts = append(conflicts, f.declConflicts(obj, name)...)
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, fmt.Errorf("%w:\n%s", ErrConflict, strings.Join(dedup(conflicts), "\n"))
+	}
+	return f.changes(idents, name)
+}
+
+// checkImporters checks that packages importing the package of obj import
+// the loaded package of obj. Otherwise the package is loaded twice or
+// discarded, references to obj in importers can't be matched and renaming
+// would break them. Vendored copies in other directories are different packages
+func (f *Finder) checkImporters(obj types.Object, packages []*Package) error {
+	pkg := f.packageByTypes(obj.Pkg())
+	for _, p := range packages {
+		if p.Types == nil || p.Types == obj.Pkg() {
+			continue
+		}
+		for _, imported := range p.Types.Imports() {
+			if imported == obj.Pkg() || imported.Path() != obj.Pkg().Path() {
+				continue
+			}
+			if other := f.packageByTypes(imported); other == nil || pkg == nil || other.Dir == pkg.Dir {
+				return fmt.Errorf("package %s imports another instance of %s, references in it can't be matched",
+					p.ImportPath, imported.Path())
+			}
+		}
+	}
+	return nil
+}
+
+// declConflicts checks conflicts of the declaration of obj renamed to name
+func (f *Finder) declConflicts(obj types.Object, name string) []string {
+	var conflicts []string
+	at := f.position(obj.Pos()).String()
+	if obj.Exported() && !token.IsExported(name) {
+		for _, pkg := range f.loadedPackages() {
+			if pkg.Types == obj.Pkg() {
+				continue
+			}
+			for ident, use := range pkg.Info.Uses {
+				if use == obj {
+					conflicts = append(conflicts, fmt.Sprintf("%s: %s is used by package %s and can't be unexported",
+						f.position(ident.Pos()), obj.Name(), pkg.ImportPath))
+				}
+			}
+		}
+	}
+	if scope := obj.Parent(); scope != nil {
+		if alt := scope.Lookup(name); alt != nil {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s conflicts with %s declared at %s",
+				at, name, alt.Name(), f.position(alt.Pos())))
+		}
+		if pkg := f.packageByTypes(obj.Pkg()); pkg != nil && scope == obj.Pkg().Scope() {
+			// file scopes contain imported package names
+			for _, file := range pkg.Files {
+				if fs := scope.Innermost(file.Pos()); fs != nil && fs.Lookup(name) != nil {
+					conflicts = append(conflicts, fmt.Sprintf("%s: %s conflicts with an imported package in %s",
+						at, name, f.position(file.Pos()).Filename))
+				}
+			}
+		}
+	}
+	switch o := obj.(type) {
+	case *types.Func:
+		recv := o.Type().(*types.Signature).Recv()
+		if recv == nil {
+			break
+		}
+		if types.IsInterface(recv.Type()) {
+			conflicts = append(conflicts, f.memberConflicts(recv.Type(), o, name)...)
+			for _, impl := range f.implementers(recv.Type(), o.Name()) {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s implements %s and would not satisfy the interface",
+					f.position(impl.obj.Pos()), impl.obj.Name(), types.TypeString(recv.Type(), relativeName(nil))))
+			}
+			break
+		}
+		t := derefType(recv.Type())
+		for _, tn := range f.namedTypes() {
+			it, ok := tn.Type().Underlying().(*types.Interface)
+			if !ok || !hasMethod(it, o.Name()) {
+				continue
+			}
+			if types.Implements(t, it) || types.Implements(types.NewPointer(t), it) {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s would not satisfy %s",
+					at, types.TypeString(t, relativeName(nil)), types.TypeString(tn.Type(), relativeName(nil))))
+			}
+		}
+		conflicts = append(conflicts, f.promotedConflicts(o, name)...)
+	case *types.Var:
+		if o.IsField() {
+			conflicts = append(conflicts, f.promotedConflicts(o, name)...)
+		}
+	}
+	return conflicts
+}
+
+// promotedConflicts checks member conflicts of all types having member, including types embedding its owner
+func (f *Finder) promotedConflicts(member types.Object, name string) []string {
+	var conflicts []string
+	for _, tn := range f.namedTypes() {
+		if sel, _, _ := types.LookupFieldOrMethod(tn.Type(), true, member.Pkg(), member.Name()); sel == member {
+			conflicts = append(conflicts, f.memberConflicts(tn.Type(), member, name)...)
+		}
+	}
+	return conflicts
+}
+
+// memberConflicts checks whether t has a field or method called name when member of t is renamed
+func (f *Finder) memberConflicts(t types.Type, member types.Object, name string) []string {
+	alt, _, _ := types.LookupFieldOrMethod(t, true, member.Pkg(), name)
+	if alt == nil {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %s already has %s at %s",
+		f.position(member.Pos()), types.TypeString(t, relativeName(nil)), name, f.position(alt.Pos()))}
+}
+
+// useConflicts checks whether ident referring obj would refer another object called name after renaming
+func (f *Finder) useConflicts(pkg *Package, ident *ast.Ident, obj types.Object, name string) []string {
+	if obj.Parent() == nil || obj.Pkg() == nil {
+		// methods and fields are checked by their types
+		return nil
+	}
+	scope := pkg.Types.Scope().Innermost(ident.Pos())
+	if scope == nil {
+		return nil
+	}
+	if pkg.Types != obj.Pkg() {
+		// qualified identifiers of other packages
+		return nil
+	}
+	for s := scope; s != nil && s != obj.Parent(); s = s.Parent() {
+		alt := s.Lookup(name)
+		// local declarations are visible after their positions, others are visible in the whole scope
+		if alt != nil && (alt.Pos() < ident.Pos() || s.Parent() == obj.Pkg().Scope()) {
+			return []string{fmt.Sprintf("%s: %s would be shadowed by %s declared at %s",
+				f.position(ident.Pos()), obj.Name(), name, f.position(alt.Pos()))}
+		}
+	}
+	return nil
+}
+
+// captureConflicts checks whether uses of other objects called name would refer obj after renaming
+func (f *Finder) captureConflicts(pkg *Package, obj types.Object, name string) []string {
+	if obj.Parent() == nil || obj.Pkg() != pkg.Types {
+		return nil
+	}
+	var conflicts []string
+	for ident, use := range pkg.Info.Uses {
+		if use.Name() != name || use == obj || use.Parent() == nil {
+			continue
+		}
+		scope := pkg.Types.Scope().Innermost(ident.Pos())
+		for s := scope; s != nil && s != use.Parent(); s = s.Parent() {
+			if s == obj.Parent() {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s declared at %s would be shadowed by renamed %s",
+					f.position(ident.Pos()), name, f.position(use.Pos()), obj.Name()))
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// changes creates changes by replacing idents with name
+func (f *Finder) changes(idents []*ast.Ident, name string) ([]*Change, error) {
+	byFile := make(map[string][]*ast.Ident)
+	seen := make(map[token.Pos]bool)
+	for _, ident := range idents {
+		if seen[ident.Pos()] {
+			continue
+		}
+		seen[ident.Pos()] = true
+		file := f.position(ident.Pos()).Filename
+		byFile[file] = append(byFile[file], ident)
+	}
+	changes := make([]*Change, 0, len(byFile))
+	for file, idents := range byFile {
+		before, err := f.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(idents, func(i, j int) bool {
+			return idents[i].Pos() < idents[j].Pos()
+		})
+		change := &Change{File: file}
+		for _, ident := range idents {
+			change.Edits = append(change.Edits, Edit{f.rangeOf(ident), name})
+		}
+		if err := change.Apply(before); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].File < changes[j].File
+	})
+	return changes, nil
+}
+
+// Apply sets Before to src and After to src with edits applied. Edits are
+// sorted and don't overlap
+func (c *Change) Apply(src []byte) error {
+	var after bytes.Buffer
+	last := 0
+	for _, edit := range c.Edits {
+		start, end := edit.Range.Start.Offset, edit.Range.End.Offset
+		if start < last || end < start || end > len(src) {
+			return fmt.Errorf("edit of %s out of range: %d-%d", c.File, start, end)
+		}
+		after.Write(src[last:start])
+		after.WriteString(edit.NewText)
+		last = end
+	}
+	after.Write(src[last:])
+	c.Before, c.After = src, after.Bytes()
+	return nil
+}
+
+// embeddedFields finds embedded fields of the type named by tn
+func embeddedFields(packages []*Package, tn types.Object) []types.Object {
+	fields := make([]types.Object, 0)
+	for _, pkg := range packages {
+		for _, def := range pkg.Info.Defs {
+			v, ok := def.(*types.Var)
+			if !ok || !v.Embedded() {
+				continue
+			}
+			if named, ok := derefType(v.Type()).(*types.Named); ok && named.Obj() == tn {
+				fields = append(fields, v)
+			}
+		}
+	}
+	return fields
+}
+
+// derefType returns the element type of pointer types
+func derefType(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+// hasMethod reports whether interface it has a method called name
+func hasMethod(it *types.Interface, name string) bool {
+	for i := 0; i < it.NumMethods(); i++ {
+		if it.Method(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dedup removes adjacent duplicated strings
+func dedup(list []string) []string {
+	result := list[:0]
+	for i, s := range list {
+		if i == 0 || s != list[i-1] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
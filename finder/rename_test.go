@@ -0,0 +1,35 @@
+package finder
+
+import (
+	"errors"
+	"go/build"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenameInvalidNames(t *testing.T) {
+	gopath, dir := workspace(t)
+	a := filepath.Join(dir, "a.go")
+	f := NewFinder(gopath, build.Default.GOROOT)
+	use := strings.Index(useSource, "use()")
+	builtin := strings.Index(useSource, "int\n")
+
+	cases := []struct {
+		pos  int
+		name string
+	}{
+		{use, "1x"},
+		{use, "_"},
+		{use, "use"},
+		{builtin, "number"},
+	}
+	for _, c := range cases {
+		if _, err := f.Rename(a, c.pos, c.name); !errors.Is(err, ErrName) {
+			t.Errorf("rename %s#%d to %s: %v, want %v", a, c.pos, c.name, err, ErrName)
+		}
+	}
+	if _, err := f.Rename(a, use, "used"); err != nil {
+		t.Errorf("rename use to used: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package finder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// resolve binds ident to the package level declaration in other files of its package.
+// The parser only resolves identifiers declared in the same file
+func (f *Finder) resolve(ident *ast.Ident) *ast.Ident {
+	if ident.Obj != nil {
+		return ident
+	}
+	file, err := f.fileByPos(ident.Pos())
+	if err != nil {
+		return ident
+	}
+	for _, pf := range f.packageFiles(file, declares(ident.Name)) {
+		if obj := lookupDecl(pf, ident.Name); obj != nil {
+			f.trace(EventIdent, ident.Pos(), "%s is declared in %s", ident.Name, f.position(pf.Pos()).Filename)
+			return &ast.Ident{
+				NamePos: ident.NamePos,
+				Name:    ident.Name,
+				Obj:     obj,
+			}
+		}
+	}
+	return ident
+}
+
+// undeclared finds the definition of an identifier which is declared out of its package
+func (f *Finder) undeclared(ident *ast.Ident) (*Definition, error) {
+	file, err := f.fileByPos(ident.Pos())
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range file.Imports {
+		if importName(spec) == ident.Name {
+			return f.importDefinition(spec)
+		}
+	}
+	if types.Universe.Lookup(ident.Name) != nil {
+		return f.builtin(ident.Name)
+	}
+	return nil, fmt.Errorf("%w: %s is not declared", ErrNotFound, ident.Name)
+}
+
+// importDefinition creates the definition of an imported package
+func (f *Finder) importDefinition(spec *ast.ImportSpec) (*Definition, error) {
+	name := spec.Name
+	if name == nil {
+		name = &ast.Ident{
+			NamePos: spec.Path.Pos(),
+			Name:    importName(spec),
+		}
+	}
+	def, err := f.definition(name, spec)
+	if err != nil {
+		return nil, err
+	}
+	def.ImportPath, _ = strconv.Unquote(spec.Path.Value)
+	return def, nil
+}
+
+// builtin finds the definition of a predeclared identifier in GOROOT/src/builtin
+func (f *Finder) builtin(name string) (*Definition, error) {
+	file, err := f.file(filepath.Join(f.GOROOT, "src", "builtin", "builtin.go"))
+	if err != nil {
+		return nil, err
+	}
+	obj := file.Scope.Lookup(name)
+	if obj == nil {
+		return nil, fmt.Errorf("%w: %s is not a builtin", ErrNotFound, name)
+	}
+	def, err := f.ToDefinition(&ast.Ident{
+		NamePos: obj.Pos(),
+		Name:    name,
+		Obj:     obj,
+	})
+	if err != nil {
+		return nil, err
+	}
+	def.Kind = KindBuiltin
+	return def, nil
+}
+
+// importName returns the name of an imported package in its file
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	p, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(p)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		// major version suffix of modules
+		name = path.Base(path.Dir(p))
+	}
+	return name
+}
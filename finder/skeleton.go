@@ -0,0 +1,173 @@
+package finder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// skeleton returns the parsed file without function bodies and object
+// resolution, or the fully parsed file if it is cached
+func (f *Finder) skeleton(file string) (*ast.File, error) {
+	return f.load(file, true)
+}
+
+// stripBodies discards function bodies and comments in them, positions of
+// declarations are kept
+func stripBodies(file *ast.File) {
+	bodies := make([]*ast.BlockStmt, 0)
+	for _, decl := range file.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+			bodies = append(bodies, fd.Body)
+			fd.Body = &ast.BlockStmt{Lbrace: fd.Body.Lbrace, Rbrace: fd.Body.Rbrace}
+		}
+	}
+	comments := file.Comments[:0]
+	for _, group := range file.Comments {
+		inBody := false
+		for _, body := range bodies {
+			if body.Lbrace < group.Pos() && group.End() <= body.Rbrace {
+				inBody = true
+				break
+			}
+		}
+		if !inBody {
+			comments = append(comments, group)
+		}
+	}
+	file.Comments = comments
+}
+
+// lookupDecl finds the package level object called name in file. Skeletons
+// have no scopes, their declarations are searched instead
+func lookupDecl(file *ast.File, name string) *ast.Object {
+	if file.Scope != nil {
+		return file.Scope.Lookup(name)
+	}
+	if name == "_" {
+		return nil
+	}
+	object := func(kind ast.ObjKind, decl ast.Node) *ast.Object {
+		obj := ast.NewObj(kind, name)
+		obj.Decl = decl
+		return obj
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.Name == name && name != "init" {
+				return object(ast.Fun, d)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.Name == name {
+						return object(ast.Typ, s)
+					}
+				case *ast.ValueSpec:
+					kind := ast.Var
+					if d.Tok == token.CONST {
+						kind = ast.Con
+					}
+					for _, n := range s.Names {
+						if n.Name == name {
+							return object(kind, s)
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// indexedSources returns files declaring symbols accepted by match, found by
+// the index of the package in dir. The package must be called pkg unless pkg
+// is empty
+func (f *Finder) indexedSources(dir, pkg string, match func(entry *IndexEntry) bool) ([]string, error) {
+	index, err := f.PackageIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	if pkg != "" && index.Name != pkg {
+		return nil, fmt.Errorf("%s is not indexed as package %s", dir, pkg)
+	}
+	files := make([]string, 0, 1)
+	seen := make(map[string]bool)
+	for i := range index.Symbols {
+		entry := &index.Symbols[i]
+		if match(entry) && !seen[entry.Range.Start.File] {
+			seen[entry.Range.Start.File] = true
+			files = append(files, entry.Range.Start.File)
+		}
+	}
+	return files, nil
+}
+
+// declares matches package level symbols called name
+func declares(name string) func(entry *IndexEntry) bool {
+	return func(entry *IndexEntry) bool {
+		return entry.Receiver == "" && entry.Name == name
+	}
+}
+
+// declaresMethod matches methods called name of the type called recv
+func declaresMethod(recv, name string) func(entry *IndexEntry) bool {
+	return func(entry *IndexEntry) bool {
+		return entry.Kind == KindMethod && entry.Receiver == recv && strings.HasSuffix(entry.Name, "."+name)
+	}
+}
+
+// importedDecl binds sel to the package level declaration in the package
+// imported as pkg, files of the package are parsed as skeletons
+func (f *Finder) importedDecl(pkg, sel *ast.Ident) (*ast.Ident, error) {
+	file, err := f.fileByPos(pkg.Pos())
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range file.Imports {
+		if importName(spec) != pkg.Name {
+			continue
+		}
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := f.findDirectory(filepath.Dir(f.position(file.Pos()).Filename), path)
+		if err != nil {
+			return nil, err
+		}
+		files, err := f.packageSources(dir)
+		if err != nil {
+			return nil, err
+		}
+		if f.CacheDir != "" {
+			// the index of the package tells which file declares sel, only
+			// that file is parsed
+			files, err = f.indexedSources(dir, "", declares(sel.Name))
+			if err != nil {
+				return nil, err
+			}
+		}
+		f.trace(EventLoad, sel.Pos(), "load skeletons of %s for %s.%s", dir, pkg.Name, sel.Name)
+		for _, source := range files {
+			pf, err := f.skeleton(source)
+			if err != nil {
+				continue
+			}
+			if obj := lookupDecl(pf, sel.Name); obj != nil && sel.IsExported() {
+				return &ast.Ident{
+					NamePos: sel.NamePos,
+					Name:    sel.Name,
+					Obj:     obj,
+				}, nil
+			}
+		}
+		return nil, &ErrUnresolved{Chain: []string{pkg.Name}, Name: sel.Name, Reason: "not declared in " + path}
+	}
+	return nil, &ErrUnresolved{Name: pkg.Name, Reason: "not declared"}
+}
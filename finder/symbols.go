@@ -0,0 +1,165 @@
+package finder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SymbolFilter filters results of symbol searches
+type SymbolFilter struct {
+	// Kinds are accepted kinds, all kinds are accepted if empty
+	Kinds []Kind `json:"kinds,omitempty"`
+	// Package is a prefix of import paths
+	Package string `json:"package,omitempty"`
+	// Exported accepts exported symbols only
+	Exported bool `json:"exported,omitempty"`
+	// Limit is the max number of results, no limit if not positive
+	Limit int `json:"limit,omitempty"`
+}
+
+// Match describes a symbol matched by a search
+type Match struct {
+	// Name is qualified by package name and receiver type, like pkg.(*T).M
+	Name       string `json:"name"`
+	Kind       Kind   `json:"kind"`
+	ImportPath string `json:"importPath"`
+	Exported   bool   `json:"exported"`
+	Path       string `json:"path"`
+	Range      Range  `json:"range"`
+	Score      int    `json:"score"`
+}
+
+// SearchSymbols finds top level declarations, methods and fields in the
+// workspace of dir whose qualified names fuzzily match query, best first
+func (f *Finder) SearchSymbols(dir, query string, filter SymbolFilter) ([]*Match, error) {
+	f, done := f.begin()
+	defer done()
+	matches := make([]*Match, 0)
+	for _, pkgDir := range packageDirs(f.workspaceOf(dir)) {
+		importPath := f.importPath(pkgDir)
+		if filter.Package != "" && !strings.HasPrefix(importPath, filter.Package) {
+			continue
+		}
+		symbols, err := f.packageSymbols(pkgDir)
+		if err != nil {
+			continue
+		}
+		for _, m := range symbols {
+			if !filter.accept(m) {
+				continue
+			}
+			score, ok := fuzzyScore(query, m.Name)
+			if !ok {
+				continue
+			}
+			match := *m
+			match.Score = score
+			matches = append(matches, &match)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Name) != len(b.Name) {
+			return len(a.Name) < len(b.Name)
+		}
+		return a.Name < b.Name
+	})
+	if filter.Limit > 0 && len(matches) > filter.Limit {
+		matches = matches[:filter.Limit]
+	}
+	return matches, nil
+}
+
+// accept reports whether m passes the filter, except the package prefix
+func (filter *SymbolFilter) accept(m *Match) bool {
+	if filter.Exported && !m.Exported {
+		return false
+	}
+	if len(filter.Kinds) == 0 {
+		return true
+	}
+	for _, kind := range filter.Kinds {
+		if kind == m.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// packageSymbols collects symbols declared in the package in dir from its index
+func (f *Finder) packageSymbols(dir string) ([]*Match, error) {
+	index, err := f.PackageIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	symbols := make([]*Match, 0, len(index.Symbols))
+	for _, s := range index.Symbols {
+		symbols = append(symbols, &Match{
+			Name:       index.Name + "." + s.Name,
+			Kind:       s.Kind,
+			ImportPath: index.ImportPath,
+			Exported:   s.Exported,
+			Path:       fmt.Sprintf("%s:%d:%d", s.Range.Start.File, s.Range.Start.Line, s.Range.Start.Column),
+			Range:      s.Range,
+		})
+	}
+	return symbols, nil
+}
+
+// fuzzyScore matches pattern as a case insensitive subsequence of name and
+// returns the score of the best alignment. Matches at word boundaries and
+// consecutive matches score higher, gaps score lower
+func fuzzyScore(pattern, name string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	p, n := []rune(strings.ToLower(pattern)), []rune(name)
+	const none = -1 << 30
+	// best[j] is the best score of the matched prefix of p ending at n[j]
+	best := make([]int, len(n))
+	next := make([]int, len(n))
+	for i := range p {
+		for j := range n {
+			next[j] = none
+			if unicode.ToLower(n[j]) != p[i] {
+				continue
+			}
+			bonus := 2
+			switch {
+			case j == 0 || !unicode.IsLetter(n[j-1]) && !unicode.IsDigit(n[j-1]):
+				bonus += 8
+			case unicode.IsUpper(n[j]) && unicode.IsLower(n[j-1]):
+				bonus += 6
+			}
+			if i == 0 {
+				next[j] = bonus
+				continue
+			}
+			for k := 0; k < j; k++ {
+				if best[k] == none {
+					continue
+				}
+				gap := -min(j-k-1, 4)
+				if k == j-1 {
+					gap = 4
+				}
+				next[j] = max(next[j], best[k]+bonus+gap)
+			}
+		}
+		best, next = next, best
+	}
+	score := none
+	for _, s := range best {
+		score = max(score, s)
+	}
+	if score == none {
+		return 0, false
+	}
+	// shorter names are more specific
+	return score*10 - len(n), true
+}
@@ -0,0 +1,140 @@
+package finder
+
+import (
+	"container/list"
+	"errors"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+)
+
+// Diagnostic describes a syntax error of a file
+type Diagnostic struct {
+	Position Position `json:"position"`
+	Message  string   `json:"message"`
+}
+
+// Diagnostics returns syntax errors of file
+func (f *Finder) Diagnostics(file string) []Diagnostic {
+	f, done := f.begin()
+	defer done()
+	file = absPath(file)
+	var list scanner.ErrorList
+	if _, err := f.file(file); err != nil {
+		var pe *ErrParse
+		if !errors.As(err, &pe) {
+			return nil
+		}
+		list = pe.Errors
+	} else {
+		f.lock.Lock()
+		list = f.diagnostics[file]
+		f.lock.Unlock()
+	}
+	diagnostics := make([]Diagnostic, 0, len(list))
+	for _, e := range list {
+		diagnostics = append(diagnostics, Diagnostic{
+			Position: Position{e.Pos.Filename, e.Pos.Line, e.Pos.Column, e.Pos.Offset},
+			Message:  e.Msg,
+		})
+	}
+	return diagnostics
+}
+
+// badIdent finds the selector at pos of file in a bad expression, statement or
+// declaration, which have no identifiers. The selector is scanned from source,
+// and its first name is bound like neighbor identifiers. It returns the stack of
+// the selector
+func (f *Finder) badIdent(file string, pos int) (*list.List, error) {
+	nodes, err := f.nodes(file, pos, pos)
+	if err != nil {
+		return nil, err
+	}
+	var bad ast.Node
+	for _, node := range nodes {
+		switch node.(type) {
+		case *ast.BadExpr, *ast.BadStmt, *ast.BadDecl:
+			bad = node
+		}
+		if bad != nil {
+			break
+		}
+	}
+	if bad == nil {
+		return nil, &ErrNoIdentAtPos{File: file, Pos: pos}
+	}
+	src, err := f.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	tf := f.tokenSet.File(bad.Pos())
+	start, end := tf.Offset(bad.Pos()), tf.Offset(bad.End())
+	if end > len(src) {
+		end = len(src)
+	}
+	// scan the source of the bad node, positions are offsets in the file
+	type item struct {
+		tok    token.Token
+		lit    string
+		offset int
+	}
+	items := make([]item, 0)
+	fset := token.NewFileSet()
+	var s scanner.Scanner
+	s.Init(fset.AddFile("", -1, end-start), src[start:end], nil, 0)
+	for {
+		p, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		items = append(items, item{tok, lit, start + fset.Position(p).Offset})
+	}
+	// the identifier at pos and the selector before it
+	last := -1
+	for i, it := range items {
+		if it.tok == token.IDENT && it.offset <= pos && pos <= it.offset+len(it.lit) {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return nil, &ErrNoIdentAtPos{File: file, Pos: pos}
+	}
+	first := last
+	for first >= 2 && items[first-1].tok == token.PERIOD && items[first-2].tok == token.IDENT {
+		first -= 2
+	}
+	stack := list.New()
+	for i := last; i >= first; i -= 2 {
+		stack.PushBack(&ast.Ident{NamePos: tf.Pos(items[i].offset), Name: items[i].lit})
+	}
+	head := stack.Back().Value.(*ast.Ident)
+	head.Obj = f.neighborObject(nodes, bad, head.Name)
+	return stack, nil
+}
+
+// neighborObject finds the object of the nearest identifier called name
+// before bad in the function or file enclosing bad
+func (f *Finder) neighborObject(nodes []ast.Node, bad ast.Node, name string) *ast.Object {
+	var scope ast.Node
+	for _, node := range nodes {
+		switch node.(type) {
+		case *ast.FuncDecl, *ast.FuncLit, *ast.File:
+			scope = node
+		}
+		if scope != nil {
+			break
+		}
+	}
+	var obj *ast.Object
+	ast.Inspect(scope, func(node ast.Node) bool {
+		if node == nil || node.Pos() >= bad.Pos() {
+			return false
+		}
+		if ident, ok := node.(*ast.Ident); ok && ident.Name == name && ident.Obj != nil {
+			obj = ident.Obj
+		}
+		return true
+	})
+	return obj
+}
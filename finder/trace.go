@@ -0,0 +1,74 @@
+package finder
+
+import (
+	"container/list"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// EventKind describes the kind of a step of resolutions
+type EventKind string
+
+// Kinds of events
+const (
+	// EventStack shows contents of a selector stack, from its first name
+	EventStack EventKind = "stack"
+	// EventIdent shows the declaration of an identifier
+	EventIdent EventKind = "ident"
+	// EventType shows the type inferred for an identifier
+	EventType EventKind = "type"
+	// EventLoad shows a file or package loaded and why
+	EventLoad EventKind = "load"
+	// EventFallback shows a resolution falling back to type checking
+	EventFallback EventKind = "fallback"
+)
+
+// Event is a step of resolutions
+type Event struct {
+	Kind    EventKind `json:"kind"`
+	Message string    `json:"message"`
+	// Path is the position of the node of the step, if any
+	Path string `json:"path,omitempty"`
+}
+
+// Tracer receives steps of resolutions. It may be called concurrently
+type Tracer interface {
+	Trace(event Event)
+}
+
+// trace sends an event to the tracer of f, pos may be token.NoPos
+func (f *Finder) trace(kind EventKind, pos token.Pos, format string, args ...interface{}) {
+	if f.Tracer == nil {
+		return
+	}
+	event := Event{Kind: kind, Message: fmt.Sprintf(format, args...)}
+	if pos.IsValid() {
+		event.Path = f.position(pos).String()
+	}
+	f.Tracer.Trace(event)
+}
+
+// stackNames returns names in stack from its first name
+func stackNames(stack *list.List) string {
+	names := make([]string, 0, stack.Len())
+	for e := stack.Back(); e != nil; e = e.Prev() {
+		if ident, ok := e.Value.(*ast.Ident); ok {
+			names = append(names, ident.Name)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// typeNames returns the last n names pushed to stack, which are names of a
+// qualified type like pkg.T
+func typeNames(stack *list.List, n int) string {
+	names := make([]string, 0, n)
+	for e := stack.Back(); e != nil && len(names) < n; e = e.Prev() {
+		if ident, ok := e.Value.(*ast.Ident); ok {
+			names = append(names, ident.Name)
+		}
+	}
+	return strings.Join(names, ".")
+}
@@ -0,0 +1,55 @@
+package finder
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// FindTypeDefinitions finds declarations of the named types of the symbol at
+// file:pos. Pointers, slices, arrays, maps and channels are stripped, so map
+// types result in definitions of both key and element types
+func (f *Finder) FindTypeDefinitions(file string, pos int) ([]*Definition, error) {
+	f, done := f.begin()
+	defer done()
+	obj, _, err := f.FindObject(file, pos)
+	if err != nil {
+		return nil, err
+	}
+	t := obj.Type()
+	if t == nil {
+		return nil, fmt.Errorf("%w: %s has no type", ErrNotFound, obj.Name())
+	}
+	defs := make([]*Definition, 0)
+	for _, tn := range namedTypesOf(t, nil) {
+		def, err := f.ObjectDefinition(tn)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("%w: %s has no named type", ErrNotFound, types.TypeString(t, relativeName(nil)))
+	}
+	return defs, nil
+}
+
+// namedTypesOf appends type names of t to names after stripping composite types
+func namedTypesOf(t types.Type, names []*types.TypeName) []*types.TypeName {
+	switch tt := t.(type) {
+	case *types.Alias:
+		return append(names, tt.Obj())
+	case *types.Named:
+		return append(names, tt.Origin().Obj())
+	case *types.Pointer:
+		return namedTypesOf(tt.Elem(), names)
+	case *types.Slice:
+		return namedTypesOf(tt.Elem(), names)
+	case *types.Array:
+		return namedTypesOf(tt.Elem(), names)
+	case *types.Chan:
+		return namedTypesOf(tt.Elem(), names)
+	case *types.Map:
+		return namedTypesOf(tt.Elem(), namedTypesOf(tt.Key(), names))
+	}
+	return names
+}
@@ -0,0 +1,83 @@
+package finder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+)
+
+// TypeInfo describes the type of an expression
+type TypeInfo struct {
+	Expression string `json:"expression"`
+	Type       string `json:"type"`
+	// Underlying is the chain of aliased and underlying types of Type
+	Underlying []string `json:"underlying,omitempty"`
+	// Value is the value of constants
+	Value string `json:"value,omitempty"`
+	Range Range  `json:"range"`
+}
+
+// TypeOf finds the type of the smallest expression enclosing the byte offsets [start, end] of file
+func (f *Finder) TypeOf(file string, start, end int) (*TypeInfo, error) {
+	f, done := f.begin()
+	defer done()
+	pkg, astFile, err := f.packageOf(file)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := f.nodesIn(astFile, start, end)
+	if err != nil {
+		return nil, err
+	}
+	qualifier := relativeName(pkg.Types)
+	for _, node := range nodes {
+		expr, ok := node.(ast.Expr)
+		if !ok {
+			continue
+		}
+		info := &TypeInfo{
+			Expression: types.ExprString(expr),
+			Range:      f.rangeOf(expr),
+		}
+		var t types.Type
+		if tv, ok := pkg.Info.Types[expr]; ok && tv.Type != nil {
+			t = tv.Type
+			if tv.Value != nil {
+				info.Value = tv.Value.String()
+			}
+		} else if ident, ok := expr.(*ast.Ident); ok && pkg.objectOf(ident) != nil {
+			// declared names and package names are not recorded as expressions
+			obj := pkg.objectOf(ident)
+			t = obj.Type()
+			if c, ok := obj.(*types.Const); ok {
+				info.Value = c.Val().String()
+			}
+		}
+		if t == nil || t == types.Typ[types.Invalid] {
+			continue
+		}
+		info.Type = types.TypeString(t, qualifier)
+		info.Underlying = underlyingChain(t, qualifier)
+		return info, nil
+	}
+	return nil, fmt.Errorf("%w: no typed expression at %d-%d", ErrNotFound, start, end)
+}
+
+// underlyingChain returns aliased types and the underlying type of t
+func underlyingChain(t types.Type, qualifier types.Qualifier) []string {
+	chain := make([]string, 0)
+	for {
+		var next types.Type
+		switch tt := t.(type) {
+		case *types.Alias:
+			next = tt.Rhs()
+		case *types.Named:
+			next = tt.Underlying()
+		}
+		if next == nil || next == t {
+			return chain
+		}
+		chain = append(chain, types.TypeString(next, qualifier))
+		t = next
+	}
+}
@@ -0,0 +1,173 @@
+package finder
+
+import (
+	"crypto/sha256"
+	"go/ast"
+	"go/types"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stamp identifies a version of a file
+type stamp struct {
+	overlay bool
+	size    int64
+	modTime time.Time
+	hash    [sha256.Size]byte
+}
+
+// stampOf reads file and returns its stamp and content
+func (f *Finder) stampOf(file string) (stamp, []byte, error) {
+	f.lock.Lock()
+	src, ok := f.overlays[absPath(file)]
+	f.lock.Unlock()
+	if ok {
+		return stamp{overlay: true, size: int64(len(src))}, src, nil
+	}
+	// stat before reading, so changes during reading are found later
+	info, err := os.Stat(file)
+	if err != nil {
+		return stamp{}, nil, err
+	}
+	src, err = os.ReadFile(file)
+	if err != nil {
+		return stamp{}, nil, err
+	}
+	return stamp{size: info.Size(), modTime: info.ModTime(), hash: sha256.Sum256(src)}, src, nil
+}
+
+// changed reports whether file on disk differs from st. Files touched without
+// changes of content are not changed, and their stamps are updated
+func (f *Finder) changed(file string, st stamp) bool {
+	if st.overlay {
+		// overlays are invalidated when they are set or removed
+		return false
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return true
+	}
+	if info.Size() == st.size && info.ModTime().Equal(st.modTime) {
+		return false
+	}
+	current, _, err := f.stampOf(file)
+	if err != nil || current.hash != st.hash {
+		return true
+	}
+	f.lock.Lock()
+	if _, ok := f.stamps[file]; ok {
+		f.stamps[file] = current
+	}
+	f.lock.Unlock()
+	return false
+}
+
+// Refresh finds parsed files changed on disk and directories with added or
+// removed files. Changed files are parsed again on next access, and packages
+// depending on them are discarded. It returns changed files
+func (f *Finder) Refresh() []string {
+	f.lock.Lock()
+	stamps := make(map[string]stamp, len(f.stamps))
+	for file, st := range f.stamps {
+		stamps[file] = st
+	}
+	f.lock.Unlock()
+	changed := make([]string, 0)
+	for file, st := range stamps {
+		if f.changed(file, st) {
+			changed = append(changed, file)
+		}
+	}
+	dirs := make([]string, 0)
+	for _, pkg := range f.loadedPackages() {
+		info, err := os.Stat(pkg.Dir)
+		if err != nil || !info.ModTime().Equal(pkg.modTime) {
+			dirs = append(dirs, pkg.Dir)
+		}
+	}
+	f.invalidate(changed, dirs...)
+	f.trim(nil)
+	return changed
+}
+
+// Watch refreshes the finder every interval until stop is closed
+func (f *Finder) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			f.Refresh()
+		}
+	}
+}
+
+// invalidate discards parsed files and packages in directories of files or
+// dirs, with packages importing them
+func (f *Finder) invalidate(files []string, dirs ...string) {
+	if len(files) == 0 && len(dirs) == 0 {
+		return
+	}
+	f.lock.Lock()
+	for _, file := range files {
+		f.forget(file)
+		dirs = append(dirs, filepath.Dir(file))
+	}
+	f.generation++
+	f.lock.Unlock()
+
+	f.packageLock.Lock()
+	defer f.packageLock.Unlock()
+	f.dropPackages(dirs)
+}
+
+// forget removes a parsed file with the lock held
+func (f *Finder) forget(file string) {
+	if e, ok := f.entries[file]; ok {
+		f.lru.Remove(e)
+		delete(f.entries, file)
+		f.memory -= f.footprint(file)
+	}
+	for _, astFile := range []*ast.File{f.astFiles[file], f.skeletons[file]} {
+		if astFile != nil {
+			f.discard(f.tokenSet.File(astFile.Pos()))
+		}
+	}
+	delete(f.astFiles, file)
+	delete(f.skeletons, file)
+	delete(f.diagnostics, file)
+	delete(f.stamps, file)
+}
+
+// dropPackages discards packages in dirs and packages importing them, with
+// the package lock held
+func (f *Finder) dropPackages(dirs []string) {
+	dropped := make(map[*types.Package]bool)
+	for _, dir := range dirs {
+		// nil packages are being loaded
+		if pkg := f.packages[dir]; pkg != nil {
+			dropped[pkg.Types] = true
+			delete(f.packages, dir)
+		}
+	}
+	// drop importers until no more packages depend on dropped packages
+	for more := len(dropped) > 0; more; {
+		more = false
+		for dir, pkg := range f.packages {
+			if pkg == nil || pkg.Types == nil {
+				continue
+			}
+			for _, imported := range pkg.Types.Imports() {
+				if dropped[imported] {
+					dropped[pkg.Types] = true
+					delete(f.packages, dir)
+					more = true
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var highlightCmd = &cobra.Command{
+	Use:   "highlight",
+	Short: "find occurrences of the symbol in the same file",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, path, pos, printer := prepare()
+		r := mustResolve(finder, newQuery(opHighlight, path, pos))
+		occurrences := r.Occurrences
+		locations := make([]location, len(occurrences))
+		for i, o := range occurrences {
+			locations[i] = location{o, o.Range.Start, fmt.Sprintf("%s %s", o.Access, o.Name)}
+		}
+		printer.output(r, func(w io.Writer) error {
+			return printer.printLocations(w, locations)
+		})
+	},
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var implementsCmd = &cobra.Command{
+	Use:   "implements",
+	Short: "find implementations of the interface or interfaces satisfied by the type",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, path, pos, printer := prepare()
+		r := mustResolve(finder, newQuery(opImplements, path, pos))
+		impls := r.Implementations
+		locations := make([]location, len(impls))
+		for i, impl := range impls {
+			locations[i] = location{impl, impl.Range.Start, fmt.Sprintf("%s %s %s", impl.Relation, impl.Kind, impl.Name)}
+		}
+		printer.output(r, func(w io.Writer) error {
+			return printer.printLocations(w, locations)
+		})
+	},
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var jumpsCmd = &cobra.Command{
+	Use:   "jumps",
+	Short: "list goto, break and continue statements to the label",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, path, pos, printer := prepare()
+		r := mustResolve(finder, newQuery(opJumps, path, pos))
+		jumps := r.Jumps
+		locations := make([]location, len(jumps))
+		for i, jump := range jumps {
+			locations[i] = location{jump, jump.Range.Start, fmt.Sprintf("%s %s", jump.Token, jump.Label)}
+		}
+		printer.output(r, func(w io.Writer) error {
+			return printer.printLocations(w, locations)
+		})
+	},
+}
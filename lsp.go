@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"github.com/kdada/gond/lsp"
+	"github.com/spf13/cobra"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "run a language server over stdin and stdout",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, _ := setup()
+		server := lsp.NewServer(finder)
+		if watch > 0 {
+			server.Refresh = false
+			go finder.Watch(watch, nil)
+		}
+		if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+			fatal(err)
+		}
+	},
+}
@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// uriToPath converts a file uri to a file path
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported uri: %s", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// pathToURI converts a file path to a file uri
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// offsetOf converts an LSP position to the byte offset in content
+func offsetOf(content []byte, pos Position) (int, error) {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := bytes.IndexByte(content[offset:], '\n')
+		if i < 0 {
+			return 0, fmt.Errorf("line %d is out of range", pos.Line)
+		}
+		offset += i + 1
+	}
+	for units := 0; units < pos.Character; {
+		r, size := utf8.DecodeRune(content[offset:])
+		if size == 0 || r == '\n' {
+			// characters beyond the line end mean the line end
+			break
+		}
+		units += utf16.RuneLen(r)
+		offset += size
+	}
+	return offset, nil
+}
+
+// positionOf converts a byte offset in content to an LSP position
+func positionOf(content []byte, offset int) Position {
+	if offset > len(content) {
+		offset = len(content)
+	}
+	line := bytes.Count(content[:offset], []byte{'\n'})
+	start := bytes.LastIndexByte(content[:offset], '\n') + 1
+	character := 0
+	for _, r := range string(content[start:offset]) {
+		character += utf16.RuneLen(r)
+	}
+	return Position{line, character}
+}
+
+// applyChange applies a content change to content
+func applyChange(content []byte, change TextDocumentContentChangeEvent) ([]byte, error) {
+	if change.Range == nil {
+		return []byte(change.Text), nil
+	}
+	start, err := offsetOf(content, change.Range.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := offsetOf(content, change.Range.End)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("invalid range %v", *change.Range)
+	}
+	result := make([]byte, 0, len(content)-(end-start)+len(change.Text))
+	result = append(result, content[:start]...)
+	result = append(result, change.Text...)
+	return append(result, content[end:]...), nil
+}
@@ -0,0 +1,403 @@
+// Package lsp implements a language server over a Finder
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/kdada/gond/finder"
+)
+
+// handler handles params of a method
+type handler func(params json.RawMessage) (interface{}, error)
+
+// Server is a language server speaking LSP over a stream
+type Server struct {
+	// Refresh refreshes the finder before each request to find files changed on disk
+	Refresh  bool
+	finder   *finder.Finder
+	handlers map[string]handler
+	shutdown bool
+}
+
+// NewServer creates a server using finder. Documents opened by clients are
+// kept as overlays of finder
+func NewServer(finder *finder.Finder) *Server {
+	s := &Server{Refresh: true, finder: finder}
+	s.handlers = map[string]handler{
+		"initialize":                  s.initialize,
+		"initialized":                 s.ignore,
+		"shutdown":                    s.shutdownServer,
+		"textDocument/didOpen":        s.didOpen,
+		"textDocument/didChange":      s.didChange,
+		"textDocument/didClose":       s.didClose,
+		"textDocument/didSave":        s.ignore,
+		"textDocument/definition":     s.definition,
+		"textDocument/typeDefinition": s.typeDefinition,
+		"textDocument/hover":          s.hover,
+		"textDocument/references":     s.references,
+		"textDocument/documentSymbol": s.documentSymbol,
+	}
+	return s
+}
+
+// Serve reads requests from r and writes responses to w until exit or EOF
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	c := newConn(r, w)
+	for {
+		msg, err := c.read()
+		if err == io.EOF {
+			return nil
+		}
+		var re *responseError
+		if errors.As(err, &re) {
+			if err := c.write(&message{Error: re, ID: nullID()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID == nil {
+			// notifications have no responses
+			if err != nil {
+				log.Printf("%s: %v", msg.Method, err)
+			}
+			continue
+		}
+		response := &message{ID: msg.ID}
+		if err == nil {
+			var raw json.RawMessage
+			if raw, err = json.Marshal(result); err == nil {
+				response.Result = &raw
+			}
+		}
+		if err != nil {
+			response.Error = toResponseError(err)
+		}
+		if err := c.write(response); err != nil {
+			return err
+		}
+	}
+}
+
+// handle calls the handler of msg
+func (s *Server) handle(msg *message) (interface{}, error) {
+	if s.shutdown {
+		return nil, &responseError{codeInvalidRequest, "server is shut down"}
+	}
+	h, ok := s.handlers[msg.Method]
+	if !ok {
+		if strings.HasPrefix(msg.Method, "$/") {
+			// optional notifications and requests
+			return nil, nil
+		}
+		return nil, &responseError{codeMethodNotFound, "method not found: " + msg.Method}
+	}
+	if s.Refresh && msg.ID != nil {
+		s.finder.Refresh()
+	}
+	result, err := h(msg.Params)
+	if errors.Is(err, finder.ErrNoIdentifier) || errors.Is(err, finder.ErrNotFound) {
+		// nothing at the position is not an error of LSP
+		return nil, nil
+	}
+	return result, err
+}
+
+// toResponseError converts err to a response error
+func toResponseError(err error) *responseError {
+	var re *responseError
+	if errors.As(err, &re) {
+		return re
+	}
+	return &responseError{codeRequestFailed, err.Error()}
+}
+
+// nullID returns a null id for responses of unreadable requests
+func nullID() *json.RawMessage {
+	id := json.RawMessage("null")
+	return &id
+}
+
+// decode decodes params to v
+func decode(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{codeInvalidParams, err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) ignore(params json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	s.finder.DocFormat = finder.DocMarkdown
+	result := &InitializeResult{}
+	result.ServerInfo.Name = "gond"
+	caps := &result.Capabilities
+	caps.TextDocumentSync.OpenClose = true
+	caps.TextDocumentSync.Change = TextDocumentSyncKindIncremental
+	caps.DefinitionProvider = true
+	caps.TypeDefinitionProvider = true
+	caps.HoverProvider = true
+	caps.ReferencesProvider = true
+	caps.DocumentSymbolProvider = true
+	return result, nil
+}
+
+func (s *Server) shutdownServer(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	path, err := uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	s.finder.SetOverlay(path, []byte(p.TextDocument.Text))
+	return nil, nil
+}
+
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	path, err := uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.finder.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range p.ContentChanges {
+		if content, err = applyChange(content, change); err != nil {
+			return nil, err
+		}
+	}
+	s.finder.SetOverlay(path, content)
+	return nil, nil
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	path, err := uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	s.finder.RemoveOverlay(path)
+	return nil, nil
+}
+
+// position converts params to a file path and a byte offset
+func (s *Server) position(p TextDocumentPositionParams) (string, int, error) {
+	path, err := uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return "", 0, err
+	}
+	content, err := s.finder.ReadFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+	offset, err := offsetOf(content, p.Position)
+	if err != nil {
+		return "", 0, &responseError{codeInvalidParams, err.Error()}
+	}
+	return path, offset, nil
+}
+
+// location converts a finder range to a location
+func (s *Server) location(r finder.Range) (Location, error) {
+	rng, err := s.lspRange(r)
+	if err != nil {
+		return Location{}, err
+	}
+	return Location{URI: pathToURI(r.Start.File), Range: rng}, nil
+}
+
+// lspRange converts a finder range to an LSP range
+func (s *Server) lspRange(r finder.Range) (Range, error) {
+	content, err := s.finder.ReadFile(r.Start.File)
+	if err != nil {
+		return Range{}, err
+	}
+	return Range{positionOf(content, r.Start.Offset), positionOf(content, r.End.Offset)}, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	path, offset, err := s.position(p)
+	if err != nil {
+		return nil, err
+	}
+	def, err := s.finder.FindDefinition(path, offset)
+	if err != nil {
+		return nil, err
+	}
+	return s.location(def.NameRange)
+}
+
+func (s *Server) typeDefinition(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	path, offset, err := s.position(p)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := s.finder.FindTypeDefinitions(path, offset)
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]Location, 0, len(defs))
+	for _, def := range defs {
+		loc, err := s.location(def.NameRange)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, loc)
+	}
+	return locations, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	path, offset, err := s.position(p)
+	if err != nil {
+		return nil, err
+	}
+	def, err := s.finder.FindDefinition(path, offset)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("%s %s.%s", def.Kind, def.Package, def.Name)
+	if info, err := s.finder.TypeOf(path, offset, offset); err == nil {
+		header = fmt.Sprintf("%s %s: %s", def.Kind, info.Expression, info.Type)
+		if info.Value != "" {
+			header += " = " + info.Value
+		}
+	}
+	value := "```go\n" + header + "\n```"
+	if def.Document != "" {
+		value += "\n\n" + def.Document
+	}
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: value}}, nil
+}
+
+func (s *Server) references(params json.RawMessage) (interface{}, error) {
+	var p ReferenceParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	path, offset, err := s.position(p.TextDocumentPositionParams)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := s.finder.FindReferences(path, offset)
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]Location, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Access == finder.AccessDeclaration && !p.Context.IncludeDeclaration {
+			continue
+		}
+		loc, err := s.location(ref.Range)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, loc)
+	}
+	return locations, nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p DocumentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	path, err := uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	symbols, err := s.finder.Outline(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	content, err := s.finder.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return documentSymbols(content, symbols), nil
+}
+
+// documentSymbols converts outline symbols to document symbols
+func documentSymbols(content []byte, symbols []*finder.Symbol) []DocumentSymbol {
+	result := make([]DocumentSymbol, 0, len(symbols))
+	for _, symbol := range symbols {
+		result = append(result, DocumentSymbol{
+			Name:   symbol.Name,
+			Detail: symbol.Detail,
+			Kind:   symbolKind(symbol),
+			Range: Range{positionOf(content, symbol.Range.Start.Offset),
+				positionOf(content, symbol.Range.End.Offset)},
+			SelectionRange: Range{positionOf(content, symbol.NameRange.Start.Offset),
+				positionOf(content, symbol.NameRange.End.Offset)},
+			Children: documentSymbols(content, symbol.Children),
+		})
+	}
+	return result
+}
+
+// symbolKind returns the LSP kind of symbol
+func symbolKind(symbol *finder.Symbol) SymbolKind {
+	switch symbol.Kind {
+	case finder.KindFunc:
+		return SymbolFunction
+	case finder.KindMethod:
+		return SymbolMethod
+	case finder.KindField:
+		return SymbolField
+	case finder.KindConst:
+		return SymbolConstant
+	case finder.KindPackage:
+		return SymbolPackage
+	case finder.KindType:
+		switch symbol.Detail {
+		case "struct":
+			return SymbolStruct
+		case "interface":
+			return SymbolInterface
+		}
+		return SymbolClass
+	}
+	return SymbolVariable
+}
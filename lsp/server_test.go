@@ -0,0 +1,181 @@
+package lsp
+
+import (
+	"encoding/json"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kdada/gond/finder"
+)
+
+const greetSource = `package p
+
+// Greeting says 你好
+const Greeting = "héllo 😀"
+
+func greet() string {
+	return Greeting
+}
+`
+
+// client sends requests to a server and reads its responses
+type client struct {
+	t    *testing.T
+	conn *conn
+	id   int
+}
+
+// notify sends a notification
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	raw, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.conn.write(&message{Method: method, Params: raw}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request and decodes the result of its response to result
+func (c *client) call(method string, params, result interface{}) {
+	c.t.Helper()
+	c.id++
+	id := json.RawMessage(strconv.Itoa(c.id))
+	raw, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.conn.write(&message{ID: &id, Method: method, Params: raw}); err != nil {
+		c.t.Fatal(err)
+	}
+	response, err := c.conn.read()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if response.Error != nil {
+		c.t.Fatalf("%s: %v", method, response.Error)
+	}
+	if string(*response.ID) != string(id) {
+		c.t.Fatalf("%s: response to %s, want %s", method, *response.ID, id)
+	}
+	if result != nil && response.Result != nil {
+		if err := json.Unmarshal(*response.Result, result); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	gopath := t.TempDir()
+	file := filepath.Join(gopath, "src", "example.com", "p", "p.go")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(greetSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(file)
+	doc := TextDocumentIdentifier{URI: uri}
+
+	requests, serverIn := io.Pipe()
+	clientIn, responses := io.Pipe()
+	server := NewServer(finder.NewFinder(gopath, build.Default.GOROOT))
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(requests, responses)
+		responses.Close()
+	}()
+	c := &client{t: t, conn: newConn(clientIn, serverIn)}
+
+	var initialized InitializeResult
+	c.call("initialize", struct{}{}, &initialized)
+	if !initialized.Capabilities.DefinitionProvider || initialized.Capabilities.TextDocumentSync.Change != TextDocumentSyncKindIncremental {
+		t.Fatalf("capabilities: %+v", initialized.Capabilities)
+	}
+	c.notify("initialized", struct{}{})
+	c.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "go", Version: 1, Text: greetSource},
+	})
+	// the use of Greeting follows an emoji taking two UTF-16 code units
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument: doc,
+		ContentChanges: []TextDocumentContentChangeEvent{{
+			Range: &Range{Position{6, 1}, Position{6, 16}},
+			Text:  "msg := \"😀\" + Greeting\n\treturn msg",
+		}},
+	})
+
+	var location Location
+	c.call("textDocument/definition", &TextDocumentPositionParams{doc, Position{7, 8}}, &location)
+	if want := (Location{uri, Range{Position{6, 1}, Position{6, 4}}}); location != want {
+		t.Errorf("definition of msg: %+v, want %+v", location, want)
+	}
+	c.call("textDocument/definition", &TextDocumentPositionParams{doc, Position{6, 15}}, &location)
+	if want := (Location{uri, Range{Position{3, 6}, Position{3, 14}}}); location != want {
+		t.Errorf("definition of Greeting: %+v, want %+v", location, want)
+	}
+
+	var hover Hover
+	c.call("textDocument/hover", &TextDocumentPositionParams{doc, Position{6, 15}}, &hover)
+	if !strings.Contains(hover.Contents.Value, "Greeting") || !strings.Contains(hover.Contents.Value, "Greeting says 你好") {
+		t.Errorf("hover of Greeting: %q", hover.Contents.Value)
+	}
+
+	var locations []Location
+	params := &ReferenceParams{TextDocumentPositionParams: TextDocumentPositionParams{doc, Position{3, 6}}}
+	params.Context.IncludeDeclaration = true
+	c.call("textDocument/references", params, &locations)
+	want := []Location{
+		{uri, Range{Position{3, 6}, Position{3, 14}}},
+		{uri, Range{Position{6, 15}, Position{6, 23}}},
+	}
+	if !reflect.DeepEqual(locations, want) {
+		t.Errorf("references to Greeting: %+v, want %+v", locations, want)
+	}
+
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	content := []byte("a😀b\n你好c\n")
+	cases := []struct {
+		offset   int
+		position Position
+	}{
+		{0, Position{0, 0}},
+		{1, Position{0, 1}},
+		{5, Position{0, 3}},
+		{6, Position{0, 4}},
+		{7, Position{1, 0}},
+		{10, Position{1, 1}},
+		{13, Position{1, 2}},
+		{15, Position{2, 0}},
+	}
+	for _, c := range cases {
+		if position := positionOf(content, c.offset); position != c.position {
+			t.Errorf("position of offset %d: %+v, want %+v", c.offset, position, c.position)
+		}
+		offset, err := offsetOf(content, c.position)
+		if err != nil || offset != c.offset {
+			t.Errorf("offset of %+v: %d, %v, want %d", c.position, offset, err, c.offset)
+		}
+	}
+	// characters beyond the line end mean the line end
+	if offset, err := offsetOf(content, Position{0, 10}); err != nil || offset != 6 {
+		t.Errorf("offset beyond the line end: %d, %v, want 6", offset, err)
+	}
+	if _, err := offsetOf(content, Position{3, 0}); err == nil {
+		t.Error("offset of a line out of range: no error")
+	}
+}
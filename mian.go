@@ -1,9 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"go/build"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 
 	"strings"
 
@@ -14,25 +18,123 @@ import (
 )
 
 var file = ""
+var docFormat = ""
+var format = ""
+var tmpl = ""
+var roots []string
+var cacheDir = ""
+var maxFiles = 0
+var maxMemory int64
+var stats = false
+var explain = false
+
+// current is the finder created by setup, whose statistics are printed by --stats
+var current *finder.Finder
 
 var rootCmd = &cobra.Command{
 	Use:   "gond",
 	Short: "go new definition",
-	Long:  ``,
+	Long: `gond finds the definition of the identifier at a position.
+
+Positions are given by --path as file.go#offset, where offset is the 0-based
+byte offset in the file like go/token.Position.Offset. Older versions took
+1-based token positions, which are one greater.
+
+Exit codes:
+  0  definition found
+  1  internal error
+  2  no identifier at the position
+  3  definition not found
+  4  invalid arguments
+  5  rename conflicts
+
+In json format, queries print one object like the results of batch, holding
+the result such as "definition" or "references", syntax errors of the file in
+"diagnostics", and "error" on failures.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		path, pos, err := splitPath(file)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		finder := finder.NewFinder(os.Getenv("GOROOT"), os.Getenv("GOPATH"))
-		def, err := finder.FindDefinition(path, pos)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		fmt.Println(def)
+		finder, path, pos, printer := prepare()
+		r := mustResolve(finder, newQuery(opDefinition, path, pos))
+		printer.output(r, func(w io.Writer) error {
+			return printer.printDefinition(w, r.Definition)
+		})
 	},
 }
 
+// prepare parses common flags and creates a finder and a printer
+func prepare() (*finder.Finder, string, int, *printer) {
+	path, pos, err := splitPath(file)
+	if err != nil {
+		fatal(usageError{err})
+	}
+	finder, printer := setup()
+	return finder, path, pos, printer
+}
+
+// setup parses common flags except the path and creates a finder and a printer
+func setup() (*finder.Finder, *printer) {
+	switch daemon {
+	case daemonOff, daemonAuto, daemonOn:
+	default:
+		fatal(usageError{fmt.Errorf("unknown daemon mode: %s", daemon)})
+	}
+	df, err := finder.ParseDocFormat(docFormat)
+	if err != nil {
+		fatal(usageError{err})
+	}
+	printer, err := newPrinter(format, tmpl)
+	if err != nil {
+		fatal(err)
+	}
+	finder := finder.NewFinder(build.Default.GOPATH, build.Default.GOROOT)
+	finder.DocFormat = df
+	finder.Roots = roots
+	finder.CacheDir = cacheDir
+	finder.MaxFiles = maxFiles
+	finder.MaxMemory = maxMemory
+	if explain {
+		finder.Tracer = &explainer{w: os.Stderr}
+	}
+	current = finder
+	return finder, printer
+}
+
+// defaultCacheDir returns the default cache directory under the user cache directory
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gond")
+}
+
+// fatal logs err and exits with the exit code of err
+func fatal(err error) {
+	log.Output(2, err.Error())
+	exit(&result{Error: newQueryError(err)})
+}
+
+// exit exits with the exit code of the error of r, r is printed in json format
+// since errors are a part of json output
+func exit(r *result) {
+	if format == formatJSON {
+		json.NewEncoder(os.Stdout).Encode(r)
+	}
+	printStats()
+	os.Exit(r.Error.Code)
+}
+
+// printStats prints statistics of the cache to stderr if --stats is set
+func printStats() {
+	if !stats || current == nil {
+		return
+	}
+	data, err := json.Marshal(current.Stats())
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(data))
+}
+
 func splitPath(path string) (string, int, error) {
 	ps := strings.LastIndex(path, "#")
 	if ps < 0 {
@@ -41,18 +143,59 @@ func splitPath(path string) (string, int, error) {
 	number := path[ps+1:]
 	path = path[:ps]
 	pos, err := strconv.Atoi(number)
-	if err != nil {
-		return "", 0, fmt.Errorf("line number not valid")
+	if err != nil || pos < 0 {
+		return "", 0, fmt.Errorf("offset not valid")
 	}
 	return path, pos, nil
 }
 
 func main() {
 	log.SetFlags(log.Lshortfile)
-	// path: /path/to/src/file/filename.go#linenumber
-	rootCmd.PersistentFlags().StringVarP(&file, "path", "p", "", "path of src file with line number")
+	// path: /path/to/src/file/filename.go#offset
+	rootCmd.PersistentFlags().StringVarP(&file, "path", "p", "", "path of src file with 0-based byte offset as file.go#offset, offsets were 1-based token positions in older versions")
+	rootCmd.PersistentFlags().StringVar(&docFormat, "doc-format", "text", "format of document: text, markdown or html")
+	rootCmd.PersistentFlags().StringVar(&format, "format", formatPlain, "output format: json, plain, godef, emacs, vim-quickfix, template or dot")
+	rootCmd.PersistentFlags().StringVar(&tmpl, "template", "", "go text/template over results, used by --format=template")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", defaultCacheDir(), "directory of the on-disk symbol index, empty to disable")
+	rootCmd.PersistentFlags().StringVar(&daemon, "daemon", daemonOff, "forward queries of the root command and query subcommands to a daemon: off, auto (fall back to in process) or on")
+	rootCmd.PersistentFlags().StringVar(&socket, "socket", socket, "unix socket of the daemon")
+	rootCmd.PersistentFlags().IntVar(&maxFiles, "max-files", 0, "max number of parsed files kept in memory, 0 for no limit")
+	rootCmd.PersistentFlags().Int64Var(&maxMemory, "max-memory", 0, "max estimated bytes of parsed files kept in memory, 0 for no limit")
+	rootCmd.PersistentFlags().BoolVar(&stats, "stats", false, "print statistics of the cache to stderr")
+	rootCmd.PersistentFlags().BoolVar(&explain, "explain", false, "print steps of resolutions to stderr, queries are not forwarded to the daemon")
+	rootCmd.PersistentFlags().StringSliceVar(&roots, "root", nil, "workspace directories searched by queries across packages, the module or the GOPATH project by default")
+	rootCmd.AddCommand(jumpsCmd)
+	rootCmd.AddCommand(refsCmd)
+	rootCmd.AddCommand(implementsCmd)
+	callersCmd.Flags().IntVar(&depth, "depth", 1, "depth of the call tree")
+	calleesCmd.Flags().IntVar(&depth, "depth", 1, "depth of the call tree")
+	rootCmd.AddCommand(callersCmd)
+	rootCmd.AddCommand(calleesCmd)
+	renameCmd.Flags().BoolVar(&diff, "diff", false, "print unified diff instead of writing files")
+	rootCmd.AddCommand(renameCmd)
+	rootCmd.AddCommand(outlineCmd)
+	symbolsCmd.Flags().StringSliceVar(&symbolKinds, "kind", nil, "kinds of symbols: func, method, type, field, var or const")
+	symbolsCmd.Flags().StringVar(&symbolPackage, "package", "", "prefix of import paths of symbols")
+	symbolsCmd.Flags().BoolVar(&exportedOnly, "exported", false, "search exported symbols only")
+	symbolsCmd.Flags().IntVar(&limit, "limit", 50, "max number of results, 0 for no limit")
+	rootCmd.AddCommand(symbolsCmd)
+	rootCmd.AddCommand(completeCmd)
+	typeCmd.Flags().IntVar(&end, "end", -1, "end byte offset of the expression, defaults to the offset of --path")
+	rootCmd.AddCommand(typeCmd)
+	rootCmd.AddCommand(typedefCmd)
+	rootCmd.AddCommand(highlightCmd)
+	lspCmd.Flags().DurationVar(&watch, "watch", 0, "poll changed files at the interval instead of checking before each request")
+	rootCmd.AddCommand(lspCmd)
+	serveCmd.Flags().DurationVar(&watch, "watch", 0, "poll changed files at the interval instead of checking before each query")
+	rootCmd.AddCommand(serveCmd)
+	batchCmd.Flags().IntVar(&jobs, "jobs", 1, "number of queries resolved concurrently")
+	rootCmd.AddCommand(batchCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	rootCmd.AddCommand(cacheCmd)
 	if err := rootCmd.Execute(); err != nil {
 		log.Println("error:", err)
-		os.Exit(-1)
+		os.Exit(exitUsage)
 	}
+	printStats()
 }
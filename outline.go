@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kdada/gond/finder"
+	"github.com/spf13/cobra"
+)
+
+var outlineCmd = &cobra.Command{
+	Use:   "outline [file]",
+	Short: "list symbols declared in the file as a tree",
+	Run: func(cmd *cobra.Command, args []string) {
+		// the file is given by the argument or by --path without offset
+		path := file
+		if len(args) > 0 {
+			path = args[0]
+		}
+		if i := strings.LastIndex(path, "#"); i >= 0 {
+			path = path[:i]
+		}
+		if path == "" || len(args) > 1 {
+			fatal(usageError{fmt.Errorf("outline requires a file")})
+		}
+		finder, printer := setup()
+		r := mustResolve(finder, newQuery(opOutline, path, 0))
+		printer.output(r, func(w io.Writer) error {
+			return printer.printOutline(w, r.Symbols)
+		})
+	},
+}
+
+// printOutline prints a tree of symbols to w
+func (p *printer) printOutline(w io.Writer, symbols []*finder.Symbol) error {
+	locations := make([]location, 0)
+	var walk func(symbols []*finder.Symbol, level int)
+	walk = func(symbols []*finder.Symbol, level int) {
+		for _, s := range symbols {
+			text := fmt.Sprintf("%s%s %s", strings.Repeat("  ", level), s.Kind, s.Name)
+			if s.Detail != "" {
+				text += " " + s.Detail
+			}
+			locations = append(locations, location{s, s.NameRange.Start, text})
+			walk(s.Children, level+1)
+		}
+	}
+	walk(symbols, 0)
+	return p.printLocations(w, locations)
+}
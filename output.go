@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/template"
+
+	"github.com/kdada/gond/finder"
+)
+
+// Output formats of definitions
+const (
+	formatJSON        = "json"
+	formatPlain       = "plain"
+	formatGodef       = "godef"
+	formatEmacs       = "emacs"
+	formatVimQuickfix = "vim-quickfix"
+	formatTemplate    = "template"
+	formatDot         = "dot"
+)
+
+// Exit codes of gond
+const (
+	exitOK       = 0
+	exitInternal = 1
+	exitNoIdent  = 2
+	exitNotFound = 3
+	exitUsage    = 4
+	exitConflict = 5
+)
+
+// usageError describes an error caused by invalid arguments
+type usageError struct {
+	error
+}
+
+// exitCode returns the exit code for err
+func exitCode(err error) int {
+	var ue usageError
+	var qe *queryError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &qe):
+		return qe.Code
+	case errors.As(err, &ue), errors.Is(err, finder.ErrOffset), errors.Is(err, finder.ErrName):
+		return exitUsage
+	case errors.Is(err, finder.ErrNoIdentifier):
+		return exitNoIdent
+	case errors.Is(err, finder.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, finder.ErrConflict):
+		return exitConflict
+	}
+	return exitInternal
+}
+
+// printDiagnostics prints syntax errors of the queried file to w
+func printDiagnostics(w io.Writer, diagnostics []finder.Diagnostic) {
+	for _, d := range diagnostics {
+		fmt.Fprintf(w, "%s:%d:%d: %s\n", d.Position.File, d.Position.Line, d.Position.Column, d.Message)
+	}
+}
+
+// explainer prints steps of resolutions to w
+type explainer struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// Trace implements finder.Tracer
+func (e *explainer) Trace(event finder.Event) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if event.Path == "" {
+		fmt.Fprintf(e.w, "%-8s %s\n", event.Kind, event.Message)
+		return
+	}
+	fmt.Fprintf(e.w, "%-8s %s (%s)\n", event.Kind, event.Message, event.Path)
+}
+
+// location is a result item located in a source file
+type location struct {
+	// Value is executed by the template format
+	Value interface{}
+	Pos   finder.Position
+	Text  string
+}
+
+// printer prints results in a format
+type printer struct {
+	format string
+	tmpl   *template.Template
+}
+
+// newPrinter creates a printer for format
+func newPrinter(format, text string) (*printer, error) {
+	p := &printer{format: format}
+	switch format {
+	case formatJSON, formatPlain, formatGodef, formatEmacs, formatVimQuickfix, formatDot:
+	case formatTemplate:
+		if text == "" {
+			return nil, usageError{fmt.Errorf("--template is required by format %s", format)}
+		}
+		tmpl, err := template.New("gond").Parse(text)
+		if err != nil {
+			return nil, usageError{err}
+		}
+		p.tmpl = tmpl
+	default:
+		return nil, usageError{fmt.Errorf("unknown format: %s", format)}
+	}
+	return p, nil
+}
+
+// output prints r by print. Results are printed as a whole in json format, so
+// results of successes and errors have the same shape as results of batches
+func (p *printer) output(r *result, print func(w io.Writer) error) {
+	var err error
+	if p.format == formatJSON {
+		err = json.NewEncoder(os.Stdout).Encode(r)
+	} else {
+		err = print(os.Stdout)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// printDefinition prints a definition to w
+func (p *printer) printDefinition(w io.Writer, def *finder.Definition) error {
+	switch p.format {
+	case formatPlain:
+		fmt.Fprintf(w, "%s %s.%s\n%s\n", def.Kind, def.Package, def.Name, def.Path)
+		if def.Declaration != "" {
+			fmt.Fprintf(w, "\n%s\n", def.Declaration)
+		}
+		if def.Document != "" {
+			fmt.Fprintf(w, "\n%s\n", def.Document)
+		}
+		return nil
+	case formatGodef:
+		_, err := fmt.Fprintln(w, def.Path)
+		return err
+	}
+	return p.printDefinitions(w, []*finder.Definition{def})
+}
+
+// printDefinitions prints a list of definitions to w
+func (p *printer) printDefinitions(w io.Writer, defs []*finder.Definition) error {
+	switch p.format {
+	case formatPlain, formatGodef:
+		for _, def := range defs {
+			if err := p.printDefinition(w, def); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	locations := make([]location, len(defs))
+	for i, def := range defs {
+		locations[i] = location{def, def.NameRange.Start, fmt.Sprintf("%s %s", def.Kind, def.Name)}
+	}
+	return p.printLocations(w, locations)
+}
+
+// printLocations prints a list of locations to w
+func (p *printer) printLocations(w io.Writer, locations []location) error {
+	if p.format == formatDot {
+		return usageError{fmt.Errorf("format %s is only supported by callers and callees", p.format)}
+	}
+	for _, l := range locations {
+		var err error
+		switch p.format {
+		case formatPlain:
+			_, err = fmt.Fprintf(w, "%s:%d:%d\t%s\n", l.Pos.File, l.Pos.Line, l.Pos.Column, l.Text)
+		case formatGodef:
+			_, err = fmt.Fprintf(w, "%s:%d:%d\n", l.Pos.File, l.Pos.Line, l.Pos.Column)
+		case formatEmacs:
+			_, err = fmt.Fprintf(w, "%s:%d.%d: %s\n", l.Pos.File, l.Pos.Line, l.Pos.Column, l.Text)
+		case formatVimQuickfix:
+			_, err = fmt.Fprintf(w, "%s:%d:%d: %s\n", l.Pos.File, l.Pos.Line, l.Pos.Column, l.Text)
+		case formatTemplate:
+			if err = p.tmpl.Execute(w, l.Value); err == nil {
+				_, err = fmt.Fprintln(w)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
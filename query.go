@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/kdada/gond/finder"
+)
+
+// Operations of queries, named after subcommands
+const (
+	opDefinition = "definition"
+	opTypedef    = "typedef"
+	opRefs       = "refs"
+	opImplements = "implements"
+	opJumps      = "jumps"
+	opCallers    = "callers"
+	opCallees    = "callees"
+	opRename     = "rename"
+	opOutline    = "outline"
+	opSymbols    = "symbols"
+	opComplete   = "complete"
+	opType       = "type"
+	opHighlight  = "highlight"
+)
+
+// query is a request resolved by a daemon or in process
+type query struct {
+	Op string `json:"op"`
+	// Path is the queried file, or the directory of symbol searches
+	Path string `json:"path"`
+	Pos  int    `json:"pos"`
+	// End is the end offset of the expression of type queries, defaults to Pos
+	End int `json:"end,omitempty"`
+	// Depth is the depth of call trees, 1 if not positive
+	Depth int `json:"depth,omitempty"`
+	// Name is the new name of renames
+	Name string `json:"name,omitempty"`
+	// Query and Filter are the query and filter of symbol searches
+	Query     string               `json:"query,omitempty"`
+	Filter    *finder.SymbolFilter `json:"filter,omitempty"`
+	DocFormat string               `json:"docFormat,omitempty"`
+	Roots     []string             `json:"roots,omitempty"`
+}
+
+// result is the response of a query
+type result struct {
+	// Query is the resolved query, set by batches
+	Query           *query                   `json:"query,omitempty"`
+	Definition      *finder.Definition       `json:"definition,omitempty"`
+	Definitions     []*finder.Definition     `json:"definitions,omitempty"`
+	References      []*finder.Reference      `json:"references,omitempty"`
+	Implementations []*finder.Implementation `json:"implementations,omitempty"`
+	Jumps           []*finder.Jump           `json:"jumps,omitempty"`
+	Call            *finder.Call             `json:"call,omitempty"`
+	// Changes of renames have edits only, files are not written
+	Changes     []*finder.Change     `json:"changes,omitempty"`
+	Symbols     []*finder.Symbol     `json:"symbols,omitempty"`
+	Matches     []*finder.Match      `json:"matches,omitempty"`
+	Completions []*finder.Completion `json:"completions,omitempty"`
+	Type        *finder.TypeInfo     `json:"type,omitempty"`
+	Occurrences []*finder.Occurrence `json:"occurrences,omitempty"`
+	Error       *queryError          `json:"error,omitempty"`
+	// Diagnostics are syntax errors of the queried file
+	Diagnostics []finder.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Kinds of query errors
+const (
+	errorUsage           = "usage"
+	errorNoIdentifier    = "no-identifier"
+	errorUnresolved      = "unresolved"
+	errorPackageNotFound = "package-not-found"
+	errorParse           = "parse"
+	errorNotFound        = "not-found"
+	errorConflict        = "conflict"
+	errorInternal        = "internal"
+)
+
+// queryError is an error of a query with its exit code and details of the
+// finder error
+type queryError struct {
+	Code    int    `json:"code"`
+	Kind    string `json:"kind,omitempty"`
+	Message string `json:"message"`
+	// Chain holds names resolved before Name of an unresolved identifier
+	Chain []string `json:"chain,omitempty"`
+	Name  string   `json:"name,omitempty"`
+	// Dirs are directories searched for a package
+	Dirs []string `json:"dirs,omitempty"`
+	// Errors are syntax errors of a file
+	Errors []string `json:"errors,omitempty"`
+}
+
+// newQueryError creates a queryError of err
+func newQueryError(err error) *queryError {
+	var qe *queryError
+	if errors.As(err, &qe) {
+		return qe
+	}
+	qe = &queryError{Code: exitCode(err), Kind: errorInternal, Message: err.Error()}
+	var unresolved *finder.ErrUnresolved
+	var notFound *finder.ErrPackageNotFound
+	var parse *finder.ErrParse
+	switch {
+	case errors.As(err, &unresolved):
+		qe.Kind = errorUnresolved
+		qe.Chain = unresolved.Chain
+		qe.Name = unresolved.Name
+	case errors.As(err, &notFound):
+		qe.Kind = errorPackageNotFound
+		qe.Name = notFound.Path
+		qe.Dirs = notFound.Dirs
+	case errors.As(err, &parse):
+		qe.Kind = errorParse
+		for _, e := range parse.Errors {
+			qe.Errors = append(qe.Errors, e.Error())
+		}
+	default:
+		switch qe.Code {
+		case exitUsage:
+			qe.Kind = errorUsage
+		case exitNoIdent:
+			qe.Kind = errorNoIdentifier
+		case exitNotFound:
+			qe.Kind = errorNotFound
+		case exitConflict:
+			qe.Kind = errorConflict
+		}
+	}
+	return qe
+}
+
+// Error implements error
+func (e *queryError) Error() string {
+	return e.Message
+}
+
+// err returns the error of r, or nil
+func (r *result) err() error {
+	if r.Error == nil {
+		return nil
+	}
+	return r.Error
+}
+
+// newQuery creates a query of op at path:pos with common flags
+func newQuery(op, path string, pos int) *query {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return &query{Op: op, Path: path, Pos: pos, DocFormat: docFormat, Roots: roots}
+}
+
+// resolve forwards q to the daemon if available, or runs q by f in process
+func resolve(f *finder.Finder, q *query) *result {
+	// steps of resolutions are traced in process only
+	if daemon != daemonOff && !explain {
+		r, err := forward(q)
+		if err == nil {
+			return r
+		}
+		if daemon == daemonOn {
+			return &result{Error: newQueryError(err)}
+		}
+	}
+	return run(f, q)
+}
+
+// forward sends q to the daemon listening on socket
+func forward(q *query) (*result, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(q); err != nil {
+		return nil, err
+	}
+	r := &result{}
+	if err := json.NewDecoder(conn).Decode(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// run resolves q by a clone of f with options of q. Panics are recovered as
+// internal errors of q, so a daemon or a batch goes on with other queries
+func run(f *finder.Finder, q *query) (r *result) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("panic in %s query at %s#%d: %v\n%s", q.Op, q.Path, q.Pos, p, debug.Stack())
+			r = &result{Error: &queryError{Code: exitInternal, Kind: errorInternal, Message: fmt.Sprint("panic: ", p)}}
+		}
+	}()
+	f = f.Clone()
+	r = &result{}
+	df := finder.DocText
+	if q.DocFormat != "" {
+		var err error
+		if df, err = finder.ParseDocFormat(q.DocFormat); err != nil {
+			r.Error = newQueryError(usageError{err})
+			return r
+		}
+	}
+	f.DocFormat = df
+	f.Roots = q.Roots
+	var err error
+	switch q.Op {
+	case "", opDefinition:
+		r.Definition, err = f.FindDefinition(q.Path, q.Pos)
+	case opTypedef:
+		r.Definitions, err = f.FindTypeDefinitions(q.Path, q.Pos)
+	case opRefs:
+		r.References, err = f.FindReferences(q.Path, q.Pos)
+	case opImplements:
+		r.Implementations, err = f.FindImplementations(q.Path, q.Pos)
+	case opJumps:
+		r.Jumps, err = f.FindLabelJumps(q.Path, q.Pos)
+	case opCallers:
+		r.Call, err = f.FindCallers(q.Path, q.Pos, max(q.Depth, 1))
+	case opCallees:
+		r.Call, err = f.FindCallees(q.Path, q.Pos, max(q.Depth, 1))
+	case opRename:
+		r.Changes, err = f.Rename(q.Path, q.Pos, q.Name)
+	case opOutline:
+		r.Symbols, err = f.Outline(q.Path)
+	case opSymbols:
+		filter := finder.SymbolFilter{}
+		if q.Filter != nil {
+			filter = *q.Filter
+		}
+		r.Matches, err = f.SearchSymbols(q.Path, q.Query, filter)
+	case opComplete:
+		r.Completions, err = f.Complete(q.Path, q.Pos)
+	case opType:
+		end := q.End
+		if end < q.Pos {
+			end = q.Pos
+		}
+		r.Type, err = f.TypeOf(q.Path, q.Pos, end)
+	case opHighlight:
+		r.Occurrences, err = f.Highlight(q.Path, q.Pos)
+	default:
+		err = usageError{fmt.Errorf("unknown op: %s", q.Op)}
+	}
+	if err != nil {
+		r.Error = newQueryError(err)
+	}
+	if q.Op != opSymbols {
+		r.Diagnostics = f.Diagnostics(q.Path)
+	}
+	return r
+}
+
+// mustResolve resolves q and exits on errors. Diagnostics are printed to
+// stderr, except in json format whose results include them
+func mustResolve(f *finder.Finder, q *query) *result {
+	r := resolve(f, q)
+	if format != formatJSON {
+		printDiagnostics(os.Stderr, r.Diagnostics)
+	}
+	if r.Error != nil {
+		log.Output(2, r.Error.Error())
+		exit(r)
+	}
+	return r
+}
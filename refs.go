@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var refsCmd = &cobra.Command{
+	Use:   "refs",
+	Short: "find all references to the symbol",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, path, pos, printer := prepare()
+		r := mustResolve(finder, newQuery(opRefs, path, pos))
+		refs := r.References
+		locations := make([]location, len(refs))
+		for i, ref := range refs {
+			text := fmt.Sprintf("%s %s", ref.Access, ref.Name)
+			if ref.Func != "" {
+				text += " in " + ref.Func
+			}
+			locations[i] = location{ref, ref.Range.Start, text}
+		}
+		printer.output(r, func(w io.Writer) error {
+			return printer.printLocations(w, locations)
+		})
+	},
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var diff = false
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <name>",
+	Short: "rename the symbol in the workspace",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fatal(usageError{fmt.Errorf("rename requires exactly one new name")})
+		}
+		finder, path, pos, _ := prepare()
+		q := newQuery(opRename, path, pos)
+		q.Name = args[0]
+		for _, change := range mustResolve(finder, q).Changes {
+			if change.After == nil {
+				// changes forwarded by the daemon have edits only
+				src, err := os.ReadFile(change.File)
+				if err != nil {
+					fatal(err)
+				}
+				if err := change.Apply(src); err != nil {
+					fatal(err)
+				}
+			}
+			if diff {
+				fmt.Print(change.Diff())
+				continue
+			}
+			info, err := os.Stat(change.File)
+			if err != nil {
+				fatal(err)
+			}
+			if err := os.WriteFile(change.File, change.After, info.Mode()); err != nil {
+				fatal(err)
+			}
+		}
+	},
+}
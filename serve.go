@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/kdada/gond/finder"
+	"github.com/spf13/cobra"
+)
+
+// Modes of forwarding queries to a daemon
+const (
+	daemonOff  = "off"
+	daemonAuto = "auto"
+	daemonOn   = "on"
+)
+
+var daemon = daemonOff
+var socket = filepath.Join(os.TempDir(), "gond.sock")
+var watch time.Duration
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "run a daemon answering queries on a unix socket",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, _ := setup()
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			fatal(usageError{errors.New("a daemon is already listening on " + socket)})
+		}
+		// remove the socket left by a dead daemon
+		os.Remove(socket)
+		listener, err := net.Listen("unix", socket)
+		if err != nil {
+			fatal(err)
+		}
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			listener.Close()
+		}()
+		if watch > 0 {
+			go finder.Watch(watch, nil)
+		}
+		serve(listener, finder)
+	},
+}
+
+// serve answers queries on connections accepted by listener until it is closed
+func serve(listener net.Listener, f *finder.Finder) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			scanner.Buffer(nil, 1<<20)
+			encoder := json.NewEncoder(conn)
+			for scanner.Scan() {
+				q := &query{}
+				r := &result{}
+				if err := json.Unmarshal(scanner.Bytes(), q); err != nil {
+					r.Error = newQueryError(usageError{err})
+				} else {
+					if watch <= 0 {
+						// find changed files before each query without a watcher
+						f.Refresh()
+					}
+					r = run(f, q)
+				}
+				if err := encoder.Encode(r); err != nil {
+					log.Println(err)
+					return
+				}
+			}
+		}()
+	}
+}
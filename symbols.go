@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kdada/gond/finder"
+	"github.com/spf13/cobra"
+)
+
+var symbolKinds []string
+var symbolPackage = ""
+var exportedOnly = false
+var limit = 50
+
+var symbolsCmd = &cobra.Command{
+	Use:   "symbols <query>",
+	Short: "search symbols in the workspace by fuzzy matching",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fatal(usageError{fmt.Errorf("symbols requires exactly one query")})
+		}
+		filter := finder.SymbolFilter{
+			Package:  symbolPackage,
+			Exported: exportedOnly,
+			Limit:    limit,
+		}
+		for _, kind := range symbolKinds {
+			filter.Kinds = append(filter.Kinds, finder.Kind(kind))
+		}
+		finder, printer := setup()
+		dir, err := os.Getwd()
+		if err != nil {
+			fatal(err)
+		}
+		q := newQuery(opSymbols, dir, 0)
+		q.Query = args[0]
+		q.Filter = &filter
+		r := mustResolve(finder, q)
+		matches := r.Matches
+		locations := make([]location, len(matches))
+		for i, m := range matches {
+			locations[i] = location{m, m.Range.Start, fmt.Sprintf("%s %s", m.Kind, m.Name)}
+		}
+		printer.output(r, func(w io.Writer) error {
+			return printer.printLocations(w, locations)
+		})
+	},
+}
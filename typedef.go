@@ -0,0 +1,19 @@
+package main
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+var typedefCmd = &cobra.Command{
+	Use:   "typedef",
+	Short: "find declarations of the type of the symbol",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, path, pos, printer := prepare()
+		r := mustResolve(finder, newQuery(opTypedef, path, pos))
+		printer.output(r, func(w io.Writer) error {
+			return printer.printDefinitions(w, r.Definitions)
+		})
+	},
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kdada/gond/finder"
+	"github.com/spf13/cobra"
+)
+
+var end = -1
+
+var typeCmd = &cobra.Command{
+	Use:   "type",
+	Short: "show the type of the expression at the position",
+	Run: func(cmd *cobra.Command, args []string) {
+		finder, path, pos, printer := prepare()
+		if end < 0 {
+			end = pos
+		}
+		if end < pos {
+			fatal(usageError{fmt.Errorf("--end %d is before offset %d", end, pos)})
+		}
+		q := newQuery(opType, path, pos)
+		q.End = end
+		r := mustResolve(finder, q)
+		printer.output(r, func(w io.Writer) error {
+			return printer.printType(w, r.Type)
+		})
+	},
+}
+
+// printType prints the type of an expression to w
+func (p *printer) printType(w io.Writer, info *finder.TypeInfo) error {
+	switch p.format {
+	case formatPlain:
+		fmt.Fprintf(w, "%s: %s\n", info.Expression, info.Type)
+		for _, t := range info.Underlying {
+			fmt.Fprintf(w, "  = %s\n", t)
+		}
+		if info.Value != "" {
+			fmt.Fprintf(w, "value: %s\n", info.Value)
+		}
+		return nil
+	}
+	return p.printLocations(w, []location{{info, info.Range.Start, fmt.Sprintf("%s: %s", info.Expression, info.Type)}})
+}